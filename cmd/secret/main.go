@@ -22,6 +22,17 @@ func main() {
 	rootCmd.AddCommand(commands.ExportKeyCmd())
 	rootCmd.AddCommand(commands.ImportKeyCmd())
 	rootCmd.AddCommand(commands.DeleteKeyCmd())
+	rootCmd.AddCommand(commands.MembersCmd())
+	rootCmd.AddCommand(commands.JoinCmd())
+	rootCmd.AddCommand(commands.RotateKeyCmd())
+	rootCmd.AddCommand(commands.RunCmd())
+	rootCmd.AddCommand(commands.CatCmd())
+	rootCmd.AddCommand(commands.EditCmd())
+	rootCmd.AddCommand(commands.GetCmd())
+	rootCmd.AddCommand(commands.SetCmd())
+	rootCmd.AddCommand(commands.GitCmd())
+	rootCmd.AddCommand(commands.HooksCmd())
+	rootCmd.AddCommand(commands.ScanCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -3,12 +3,17 @@ module github.com/Avdushin/secret
 go 1.24.6
 
 require (
+	filippo.io/age v1.2.1
+	github.com/ProtonMail/go-crypto v1.3.0
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/crypto v0.33.0
 	golang.org/x/term v0.34.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/cloudflare/circl v1.6.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,601 @@
+package backends
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/Avdushin/secret/pkg/config"
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	Register("age", func(cfg *config.Config) (Backend, error) {
+		return NewAgeBackend(cfg)
+	})
+}
+
+// AgeBackend шифрует файлы в формате age для получателей из cfg.Recipients.
+// Получатели — публичные ключи age (age1...) или SSH (ssh-ed25519, ssh-rsa).
+// Для расшифровки используются идентичности age и приватные SSH-ключи пользователя.
+type AgeBackend struct {
+	cfg      *config.Config
+	keysFile string
+	skipped  map[string]bool
+}
+
+// ageIdentity — загруженный приватный ключ вместе с его публичной частью
+type ageIdentity struct {
+	identity  age.Identity
+	recipient string
+	source    string
+	// Публичная часть SSH-ключа, защищенного паролем: по ней CanDecrypt
+	// проверяет заголовок, не запрашивая парольную фразу
+	encryptedSSH ssh.PublicKey
+}
+
+func NewAgeBackend(cfg *config.Config) (*AgeBackend, error) {
+	keysFile, err := ageKeysFile()
+	if err != nil {
+		return nil, err
+	}
+	return &AgeBackend{cfg: cfg, keysFile: keysFile}, nil
+}
+
+// ageKeysFile возвращает файл идентичностей age, которые создает secret
+func ageKeysFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("не удалось определить директорию конфигурации: %v", err)
+	}
+	return filepath.Join(dir, "secret", "age", "keys.txt"), nil
+}
+
+func (a *AgeBackend) Name() string { return "age" }
+
+func (a *AgeBackend) Ext() string { return ".age" }
+
+func (a *AgeBackend) Encrypt(file string) error {
+	plaintext, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return err
+	}
+	if err := a.EncryptBytes(file, plaintext); err != nil {
+		return err
+	}
+	outFile := file + a.Ext()
+	fmt.Printf("✅ Файл %s зашифрован в %s\n", file, outFile)
+	return nil
+}
+
+// EncryptBytes шифрует содержимое для файла file, не читая его с диска
+func (a *AgeBackend) EncryptBytes(file string, plaintext []byte) error {
+	sealed, err := a.Seal(file, plaintext)
+	if err != nil {
+		return err
+	}
+	outFile := file + a.Ext()
+	if err := os.WriteFile(outFile, sealed, 0644); err != nil {
+		return err
+	}
+	// Создаем .example файл
+	if err := writeExampleFile(file, plaintext, a.cfg.Markers); err != nil {
+		return fmt.Errorf("не удалось создать .example файл: %v", err)
+	}
+	return nil
+}
+
+// Seal шифрует данные для получателей файла file
+func (a *AgeBackend) Seal(file string, plaintext []byte) ([]byte, error) {
+	keys, err := a.cfg.FileRecipients(file)
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := parseAgeRecipients(keys)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *AgeBackend) Decrypt(file string) error {
+	plaintext, err := a.DecryptBytes(file)
+	if err != nil {
+		return err
+	}
+
+	outFile := strings.TrimSuffix(file, filepath.Ext(file))
+	if err := os.WriteFile(outFile, plaintext, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s расшифрован в %s\n", file, outFile)
+	return nil
+}
+
+func (a *AgeBackend) DecryptBytes(file string) ([]byte, error) {
+	ciphertext, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return nil, err
+	}
+	return a.Open(ciphertext)
+}
+
+// Open расшифровывает данные ключами age и SSH пользователя
+func (a *AgeBackend) Open(ciphertext []byte) ([]byte, error) {
+	identities, err := a.identities()
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("не найдено ни одного приватного ключа age или SSH (укажите SECRET_AGE_IDENTITY)")
+	}
+	ids := make([]age.Identity, len(identities))
+	for i, id := range identities {
+		ids[i] = id.identity
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка дешифровки: %v", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка дешифровки: %v", err)
+	}
+	return plaintext, nil
+}
+
+// CanDecrypt пробует открыть заголовок файла имеющимися ключами.
+// Для SSH-ключей, защищенных паролем, проверяется только наличие
+// получателя в заголовке: парольная фраза не запрашивается.
+func (a *AgeBackend) CanDecrypt(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	identities, err := a.identities()
+	if err != nil {
+		return err
+	}
+	ids := make([]age.Identity, len(identities))
+	for i, id := range identities {
+		ids[i] = id.identity
+		if id.encryptedSSH != nil {
+			ids[i] = sshStanzaMatch{id.encryptedSSH}
+		}
+	}
+	if _, err := age.Decrypt(f, ids...); err != nil && !errors.Is(err, errSSHStanzaMatch) {
+		return fmt.Errorf("нет подходящего ключа: %v", err)
+	}
+	return nil
+}
+
+// errSSHStanzaMatch — файл зашифрован для SSH-ключа из sshStanzaMatch
+var errSSHStanzaMatch = errors.New("файл зашифрован для SSH-ключа")
+
+// sshStanzaMatch ищет в заголовке age получателя с публичным SSH-ключом,
+// не расшифровывая ключ файла (как agessh.EncryptedSSHIdentity до запроса
+// парольной фразы)
+type sshStanzaMatch struct {
+	pubKey ssh.PublicKey
+}
+
+func (m sshStanzaMatch) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	sum := sha256.Sum256(m.pubKey.Marshal())
+	tag := base64.RawStdEncoding.EncodeToString(sum[:4])
+	for _, s := range stanzas {
+		if s.Type == m.pubKey.Type() && len(s.Args) > 0 && s.Args[0] == tag {
+			return nil, errSSHStanzaMatch
+		}
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+// parseAgeRecipients разбирает публичные ключи age и SSH
+func parseAgeRecipients(keys []string) ([]age.Recipient, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("не настроены получатели (recipients). Сначала выполните: secret init")
+	}
+	recipients := make([]age.Recipient, 0, len(keys))
+	for _, key := range keys {
+		r, err := parseAgeRecipient(key)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+func parseAgeRecipient(key string) (age.Recipient, error) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "age1") {
+		r, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("неверный получатель age %q: %v", key, err)
+		}
+		return r, nil
+	}
+	if strings.HasPrefix(key, "ssh-") {
+		r, err := agessh.ParseRecipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("неверный SSH-получатель %q: %v", key, err)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("неизвестный формат получателя %q (ожидается age1... или ssh-...)", key)
+}
+
+// sameRecipient сравнивает ключи без учета комментария SSH
+func sameRecipient(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	if len(fa) == 0 || len(fb) == 0 {
+		return false
+	}
+	if len(fa) >= 2 && len(fb) >= 2 {
+		return fa[0] == fb[0] && fa[1] == fb[1]
+	}
+	return fa[0] == fb[0]
+}
+
+// identities загружает приватные ключи пользователя:
+// SECRET_AGE_IDENTITY, файл ключей secret и стандартные SSH-ключи.
+// Стандартный SSH-ключ, который не удалось прочитать (например, неподдерживаемого
+// типа), пропускается с предупреждением, чтобы не мешать остальным ключам.
+func (a *AgeBackend) identities() ([]ageIdentity, error) {
+	var paths, defaults []string
+	if env := os.Getenv("SECRET_AGE_IDENTITY"); env != "" {
+		paths = append(paths, filepath.SplitList(env)...)
+	}
+	paths = append(paths, a.keysFile)
+	if home, err := os.UserHomeDir(); err == nil {
+		defaults = []string{
+			filepath.Join(home, ".ssh", "id_ed25519"),
+			filepath.Join(home, ".ssh", "id_rsa"),
+		}
+	}
+
+	var result []ageIdentity
+	for i, path := range append(paths, defaults...) {
+		optional := i >= len(paths)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		var ids []ageIdentity
+		if err == nil {
+			ids, err = parseIdentityFile(path, data)
+		}
+		if err != nil && optional {
+			a.warnSkipped(path, err)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		result = append(result, ids...)
+	}
+	return result, nil
+}
+
+// warnSkipped сообщает о пропущенном SSH-ключе один раз за запуск.
+// Предупреждение пишется в stderr: stdout фильтров git занят данными.
+func (a *AgeBackend) warnSkipped(path string, err error) {
+	if a.skipped[path] {
+		return
+	}
+	if a.skipped == nil {
+		a.skipped = map[string]bool{}
+	}
+	a.skipped[path] = true
+	fmt.Fprintf(os.Stderr, "⚠️ SSH-ключ %s пропущен: %v\n", path, err)
+}
+
+func parseIdentityFile(path string, data []byte) ([]ageIdentity, error) {
+	if bytes.Contains(data, []byte("PRIVATE KEY-----")) {
+		id, err := parseSSHIdentity(path, data)
+		if err != nil {
+			return nil, err
+		}
+		return []ageIdentity{id}, nil
+	}
+
+	var result []ageIdentity
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := age.ParseX25519Identity(line)
+		if err != nil {
+			return nil, fmt.Errorf("неверная идентичность age в %s: %v", path, err)
+		}
+		result = append(result, ageIdentity{identity: id, recipient: id.Recipient().String(), source: path})
+	}
+	return result, scanner.Err()
+}
+
+func parseSSHIdentity(path string, pemBytes []byte) (ageIdentity, error) {
+	// Публичная часть нужна для сопоставления с получателями и для зашифрованных ключей
+	var pubKey ssh.PublicKey
+	var recipient string
+	if pubData, err := os.ReadFile(path + ".pub"); err == nil {
+		recipient = strings.TrimSpace(string(pubData))
+		pubKey, _, _, _, _ = ssh.ParseAuthorizedKey(pubData)
+	}
+
+	id, err := agessh.ParseIdentity(pemBytes)
+	var encrypted ssh.PublicKey
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if pubKey == nil {
+			pubKey = missing.PublicKey
+		}
+		if pubKey == nil {
+			return ageIdentity{}, fmt.Errorf("ключ %s защищен паролем, но нет %s.pub", path, path)
+		}
+		id, err = agessh.NewEncryptedSSHIdentity(pubKey, pemBytes, func() ([]byte, error) {
+			return readPassphrase(fmt.Sprintf("Парольная фраза для %s: ", path))
+		})
+		encrypted = pubKey
+	}
+	if err != nil {
+		return ageIdentity{}, fmt.Errorf("не удалось прочитать SSH-ключ %s: %v", path, err)
+	}
+	if recipient == "" && pubKey != nil {
+		recipient = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pubKey)))
+	}
+	return ageIdentity{identity: id, recipient: recipient, source: path, encryptedSSH: encrypted}, nil
+}
+
+// GenerateKey создает новую идентичность age и добавляет ее в файл ключей.
+// Параметры GPG-ключа для age не используются.
+func (a *AgeBackend) GenerateKey(p KeyParams) (string, error) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", fmt.Errorf("ошибка генерации ключа: %v", err)
+	}
+	comment := strings.TrimSpace(p.Name + " " + p.Email)
+	if err := a.appendIdentity(id, comment); err != nil {
+		return "", err
+	}
+	return id.Recipient().String(), nil
+}
+
+func (a *AgeBackend) appendIdentity(id *age.X25519Identity, comment string) error {
+	if err := os.MkdirAll(filepath.Dir(a.keysFile), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.keysFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if comment != "" {
+		fmt.Fprintf(f, "# %s\n", comment)
+	}
+	fmt.Fprintf(f, "# public key: %s\n%s\n", id.Recipient(), id)
+	return nil
+}
+
+func (a *AgeBackend) ExportKey(keyID string, secret bool) ([]byte, error) {
+	id, err := a.findIdentity(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if !secret {
+		return []byte(id.recipient + "\n"), nil
+	}
+	x25519, ok := id.identity.(*age.X25519Identity)
+	if !ok {
+		return nil, fmt.Errorf("экспорт приватного SSH-ключа %s: %w", id.source, ErrNotSupported)
+	}
+	return []byte(fmt.Sprintf("# public key: %s\n%s\n", x25519.Recipient(), x25519)), nil
+}
+
+func (a *AgeBackend) ImportKey(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ids, err := parseIdentityFile(path, data)
+	if err != nil {
+		return err
+	}
+	existing, err := a.identities()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		x25519, ok := id.identity.(*age.X25519Identity)
+		if !ok {
+			return fmt.Errorf("импорт SSH-ключей не требуется: укажите путь в SECRET_AGE_IDENTITY")
+		}
+		if hasRecipient(existing, id.recipient) {
+			continue
+		}
+		if err := a.appendIdentity(x25519, "imported from "+filepath.Base(path)); err != nil {
+			return err
+		}
+		fmt.Printf("🔑 Импортирован ключ %s\n", id.recipient)
+	}
+	return nil
+}
+
+// AddRecipient принимает публичный ключ age/SSH или файл с такими ключами
+// (например, id_ed25519.pub). Связки ключей у age нет, поэтому ничего не импортируется.
+func (a *AgeBackend) AddRecipient(ref string) ([]string, error) {
+	lines := []string{ref}
+	if data, err := os.ReadFile(ref); err == nil {
+		lines = strings.Split(string(data), "\n")
+	}
+	var recipients []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "AGE-SECRET-KEY-") || strings.Contains(line, "PRIVATE KEY") {
+			return nil, fmt.Errorf("%s содержит приватный ключ: участнику нужно передать только публичный", ref)
+		}
+		if _, err := parseAgeRecipient(line); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, line)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("в %s не найдено публичных ключей", ref)
+	}
+	return recipients, nil
+}
+
+// Ключи age предназначены только для шифрования и не умеют подписывать
+func (a *AgeBackend) Sign(keyID string, data []byte) ([]byte, error) { return nil, ErrNotSupported }
+
+func (a *AgeBackend) Verify(data, signature, publicKey []byte) (string, error) {
+	return "", ErrNotSupported
+}
+
+func hasRecipient(ids []ageIdentity, recipient string) bool {
+	for _, id := range ids {
+		if sameRecipient(id.recipient, recipient) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindKey возвращает публичный ключ первой идентичности, которая
+// является получателем проекта (или просто первой, если получатели не заданы)
+func (a *AgeBackend) FindKey(query string) (string, error) {
+	ids, err := a.identities()
+	if err != nil {
+		return "", err
+	}
+	recipients := a.cfg.EncryptionRecipients()
+	for _, id := range ids {
+		if id.recipient == "" {
+			continue
+		}
+		if len(recipients) == 0 {
+			return id.recipient, nil
+		}
+		for _, r := range recipients {
+			if sameRecipient(r, id.recipient) {
+				return id.recipient, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("не найден приватный ключ ни для одного получателя проекта")
+}
+
+func (a *AgeBackend) findIdentity(keyID string) (*ageIdentity, error) {
+	ids, err := a.identities()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ids {
+		if sameRecipient(ids[i].recipient, keyID) {
+			return &ids[i], nil
+		}
+	}
+	return nil, fmt.Errorf("приватный ключ для %s не найден", keyID)
+}
+
+func (a *AgeBackend) KeyInfo(keyID string) (*KeyInfo, error) {
+	id, err := a.findIdentity(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyInfo{
+		ID:          id.recipient,
+		Name:        id.source,
+		Fingerprint: id.recipient,
+	}, nil
+}
+
+// DeleteKey удаляет идентичность из файла ключей secret.
+// SSH-ключи пользователя не удаляются.
+func (a *AgeBackend) DeleteKey(keyID string) error {
+	data, err := os.ReadFile(a.keysFile)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	var kept []string
+	removed := false
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "AGE-SECRET-KEY-") {
+			if id, err := age.ParseX25519Identity(line); err == nil && id.Recipient().String() == keyID {
+				removed = true
+				// Удаляем и комментарии, относящиеся к ключу
+				for len(kept) > 0 && strings.HasPrefix(kept[len(kept)-1], "#") {
+					kept = kept[:len(kept)-1]
+				}
+				continue
+			}
+		}
+		kept = append(kept, lines[i])
+	}
+	if !removed {
+		return fmt.Errorf("ключ %s не найден в %s", keyID, a.keysFile)
+	}
+	return os.WriteFile(a.keysFile, []byte(strings.Join(kept, "\n")), 0600)
+}
+
+func (a *AgeBackend) ListKeys() (string, error) {
+	ids, err := a.identities()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&sb, "%s\n    %s\n", id.source, id.recipient)
+	}
+	return sb.String(), nil
+}
+
+func (a *AgeBackend) Check() error {
+	recipients, err := parseAgeRecipients(a.cfg.EncryptionRecipients())
+	if err != nil {
+		return err
+	}
+	w, err := age.Encrypt(io.Discard, recipients...)
+	if err != nil {
+		return fmt.Errorf("ошибка тестового шифрования: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if _, err := a.FindKey(""); err != nil {
+		return err
+	}
+	return nil
+}
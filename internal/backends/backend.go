@@ -0,0 +1,161 @@
+package backends
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// ErrNotSupported возвращается, если операция не поддерживается бэкендом
+var ErrNotSupported = errors.New("операция не поддерживается бэкендом")
+
+// Backend — общий интерфейс бэкендов шифрования
+type Backend interface {
+	// Name возвращает имя бэкенда, как оно указано в конфиге
+	Name() string
+	// Ext возвращает расширение зашифрованных файлов (например, ".gpg")
+	Ext() string
+
+	// Encrypt шифрует файл и создает для него .example
+	Encrypt(file string) error
+	// EncryptBytes шифрует содержимое из памяти в file+Ext() и обновляет
+	// .example, не записывая открытый текст на диск
+	EncryptBytes(file string, plaintext []byte) error
+	// Decrypt расшифровывает файл рядом с зашифрованным
+	Decrypt(file string) error
+	// DecryptBytes расшифровывает файл в память, ничего не записывая на диск
+	DecryptBytes(file string) ([]byte, error)
+	// CanDecrypt проверяет, может ли текущий пользователь расшифровать файл,
+	// не расшифровывая его и не запрашивая парольную фразу
+	CanDecrypt(file string) error
+	// Seal шифрует данные в памяти для получателей файла file
+	Seal(file string, plaintext []byte) ([]byte, error)
+	// Open расшифровывает данные, зашифрованные Seal
+	Open(ciphertext []byte) ([]byte, error)
+
+	// GenerateKey создает новый ключ и возвращает его ID
+	GenerateKey(params KeyParams) (string, error)
+	// ExportKey возвращает публичную (или приватную при secret=true) часть ключа
+	ExportKey(keyID string, secret bool) ([]byte, error)
+	// ImportKey импортирует ключ из файла
+	ImportKey(path string) error
+	// AddRecipient принимает публичный ключ участника (путь к файлу или
+	// отпечаток уже известного ключа) и возвращает значения для cfg.Recipients
+	AddRecipient(ref string) ([]string, error)
+	// Sign создает отсоединенную armored-подпись данных ключом keyID
+	Sign(keyID string, data []byte) ([]byte, error)
+	// Verify проверяет подпись данных публичным ключом publicKey (без импорта)
+	// и возвращает отпечаток подписавшего ключа. publicKey должен содержать
+	// ровно один основной ключ.
+	Verify(data, signature, publicKey []byte) (string, error)
+	// FindKey ищет приватный ключ по подстроке в uid (пустая строка — первый найденный)
+	FindKey(query string) (string, error)
+	// KeyInfo возвращает информацию о ключе или ошибку, если ключ не найден
+	KeyInfo(keyID string) (*KeyInfo, error)
+	// DeleteKey удаляет приватную и публичную части ключа
+	DeleteKey(keyID string) error
+	// ListKeys возвращает список доступных ключей в читаемом виде
+	ListKeys() (string, error)
+
+	// Check проверяет, что бэкенд готов к работе с ключом проекта
+	Check() error
+}
+
+// KeyParams — параметры генерации ключа
+type KeyParams struct {
+	Name       string
+	Email      string
+	Comment    string
+	Type       string
+	Length     int
+	Expire     string
+	Passphrase string
+}
+
+// KeyInfo — информация о ключе
+type KeyInfo struct {
+	ID          string
+	Name        string
+	Email       string
+	Fingerprint string
+}
+
+// Factory создает бэкенд для конфига проекта
+type Factory func(cfg *config.Config) (Backend, error)
+
+var registry = map[string]Factory{}
+
+// Register регистрирует бэкенд под именем name
+func Register(name string, factory Factory) {
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("бэкенд %s уже зарегистрирован", name))
+	}
+	registry[name] = factory
+}
+
+// Names возвращает отсортированный список зарегистрированных бэкендов
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New создает бэкенд, указанный в cfg.Backend
+func New(cfg *config.Config) (Backend, error) {
+	factory, ok := registry[cfg.Backend]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный бэкенд %q (доступны: %s)", cfg.Backend, strings.Join(Names(), ", "))
+	}
+	backend, err := factory(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mode := cfg.Mode
+	if mode == "" && cfg.Markers != nil {
+		// Метки имеют смысл только при шифровании отдельных значений
+		mode = config.ModeValues
+	}
+	switch mode {
+	case "", config.ModeFile:
+		if cfg.Markers != nil {
+			return nil, fmt.Errorf("markers работают только в mode: %s", config.ModeValues)
+		}
+		return backend, nil
+	case config.ModeValues:
+		return &valuesBackend{Backend: backend, cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("неизвестный режим шифрования %q (доступны: %s, %s)", cfg.Mode, config.ModeFile, config.ModeValues)
+}
+
+// LooksEncrypted сообщает, похоже ли содержимое на шифротекст: сообщение
+// OpenPGP (двоичное или armored), файл age или файл mode: values
+func LooksEncrypted(content []byte) bool {
+	if len(content) == 0 {
+		return false
+	}
+	for _, prefix := range []string{"-----BEGIN PGP MESSAGE-----", "age-encryption.org/", "-----BEGIN AGE ENCRYPTED FILE-----"} {
+		if bytes.HasPrefix(content, []byte(prefix)) {
+			return true
+		}
+	}
+	if lineHeader.Match(content) || jsonHeader.Match(content) {
+		return true
+	}
+	// Первый пакет OpenPGP: зашифрованный ключ сессии (1) или симметричный (3)
+	c := content[0]
+	if c&0x80 == 0 {
+		return false
+	}
+	tag := c & 0x3f
+	if c&0x40 == 0 {
+		tag = (c & 0x3c) >> 2
+	}
+	return tag == 1 || tag == 3
+}
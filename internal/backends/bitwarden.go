@@ -0,0 +1,403 @@
+package backends
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Avdushin/secret/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register("bitwarden", func(cfg *config.Config) (Backend, error) {
+		return NewBitwardenBackend(cfg), nil
+	})
+}
+
+// BitwardenBackend хранит каждый секретный файл как защищенную заметку
+// Bitwarden, управляя хранилищем через CLI bw. Рядом с файлом создается
+// указатель .bw с ID заметки.
+type BitwardenBackend struct {
+	cfg     *config.Config
+	bin     string
+	session string
+	synced  bool
+}
+
+// bitwardenPointer — содержимое файла-указателя .bw
+type bitwardenPointer struct {
+	ID     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	Format string `yaml:"format"`
+}
+
+// bitwardenItem — поля элемента хранилища, которые использует secret
+type bitwardenItem struct {
+	ID             string         `json:"id,omitempty"`
+	OrganizationID *string        `json:"organizationId"`
+	CollectionIDs  []string       `json:"collectionIds,omitempty"`
+	FolderID       *string        `json:"folderId"`
+	Type           int            `json:"type"`
+	Name           string         `json:"name"`
+	Notes          string         `json:"notes"`
+	SecureNote     map[string]int `json:"secureNote"`
+}
+
+const bitwardenSecureNote = 2
+
+func NewBitwardenBackend(cfg *config.Config) *BitwardenBackend {
+	bin := os.Getenv("SECRET_BW_BIN")
+	if bin == "" {
+		bin = "bw"
+	}
+	return &BitwardenBackend{cfg: cfg, bin: bin, session: os.Getenv("BW_SESSION")}
+}
+
+func (b *BitwardenBackend) Name() string { return "bitwarden" }
+
+func (b *BitwardenBackend) Ext() string { return ".bw" }
+
+// Encrypt создает или обновляет защищенную заметку с содержимым файла
+func (b *BitwardenBackend) Encrypt(file string) error {
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return err
+	}
+	if err := b.EncryptBytes(file, content); err != nil {
+		return err
+	}
+	pointer, err := readBitwardenPointer(file + b.Ext())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s сохранен в Bitwarden (%s)\n", file, pointer.Name)
+	return nil
+}
+
+// EncryptBytes сохраняет содержимое файла file в заметку, не читая его с диска
+func (b *BitwardenBackend) EncryptBytes(file string, content []byte) error {
+	outFile := file + b.Ext()
+	pointer := bitwardenPointer{Name: b.itemName(file), Format: "text"}
+	notes := string(content)
+	if !utf8.Valid(content) {
+		pointer.Format = "base64"
+		notes = base64.StdEncoding.EncodeToString(content)
+	}
+
+	// Если заметка уже есть, обновляем ее, сохраняя ID
+	if old, err := readBitwardenPointer(outFile); err == nil && old.ID != "" {
+		pointer.ID = old.ID
+	} else if found, err := b.findItem(pointer.Name); err != nil {
+		return err
+	} else if found != nil {
+		pointer.ID = found.ID
+	}
+
+	if pointer.ID == "" {
+		item := b.newItem(pointer.Name)
+		item.Notes = notes
+		var created bitwardenItem
+		if err := b.runJSON(&created, encodeBitwardenItem(item), "create", "item"); err != nil {
+			return fmt.Errorf("ошибка создания заметки Bitwarden: %v", err)
+		}
+		pointer.ID = created.ID
+	} else if err := b.updateNotes(pointer.ID, notes); err != nil {
+		return fmt.Errorf("ошибка обновления заметки Bitwarden: %v", err)
+	}
+
+	if err := writeBitwardenPointer(outFile, pointer); err != nil {
+		return err
+	}
+	// Создаем .example файл
+	if err := writeExampleFile(file, content, b.cfg.Markers); err != nil {
+		return fmt.Errorf("не удалось создать .example файл: %v", err)
+	}
+	return nil
+}
+
+// Decrypt восстанавливает файл из заметки, указанной в .bw
+func (b *BitwardenBackend) Decrypt(file string) error {
+	content, err := b.DecryptBytes(file)
+	if err != nil {
+		return err
+	}
+	outFile := strings.TrimSuffix(file, b.Ext())
+	if err := os.WriteFile(outFile, content, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s восстановлен из Bitwarden в %s\n", file, outFile)
+	return nil
+}
+
+func (b *BitwardenBackend) DecryptBytes(file string) ([]byte, error) {
+	pointer, err := readBitwardenPointer(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var item *bitwardenItem
+	if pointer.ID != "" {
+		item, err = b.getItem(pointer.ID)
+	} else {
+		item, err = b.findItem(pointer.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("заметка %s не найдена в Bitwarden", pointer.Name)
+	}
+
+	content := []byte(item.Notes)
+	if pointer.Format == "base64" {
+		if content, err = base64.StdEncoding.DecodeString(item.Notes); err != nil {
+			return nil, fmt.Errorf("повреждено содержимое заметки %s: %v", pointer.Name, err)
+		}
+	}
+	return content, nil
+}
+
+// itemName формирует имя заметки: secret:<проект>/<файл>
+func (b *BitwardenBackend) itemName(file string) string {
+	return fmt.Sprintf("secret:%s/%s", b.cfg.ProjectName, filepath.ToSlash(filepath.Clean(file)))
+}
+
+func (b *BitwardenBackend) newItem(name string) *bitwardenItem {
+	item := &bitwardenItem{
+		Type:       bitwardenSecureNote,
+		Name:       name,
+		SecureNote: map[string]int{"type": 0},
+	}
+	if bw := b.cfg.Bitwarden; bw != nil {
+		if bw.OrganizationID != "" {
+			item.OrganizationID = &bw.OrganizationID
+		}
+		if bw.CollectionID != "" {
+			item.CollectionIDs = []string{bw.CollectionID}
+		}
+		if bw.FolderID != "" {
+			item.FolderID = &bw.FolderID
+		}
+	}
+	return item
+}
+
+// encodeBitwardenItem кодирует элемент так, как его ожидают bw create/edit
+func encodeBitwardenItem(item any) []byte {
+	data, _ := json.Marshal(item)
+	return []byte(base64.StdEncoding.EncodeToString(data))
+}
+
+// updateNotes меняет только текст заметки, сохраняя остальные поля элемента
+func (b *BitwardenBackend) updateNotes(id, notes string) error {
+	if err := b.sync(); err != nil {
+		return err
+	}
+	var raw map[string]any
+	if err := b.runJSON(&raw, nil, "get", "item", id); err != nil {
+		return err
+	}
+	raw["notes"] = notes
+	return b.runJSON(nil, encodeBitwardenItem(raw), "edit", "item", id)
+}
+
+func (b *BitwardenBackend) getItem(id string) (*bitwardenItem, error) {
+	if err := b.sync(); err != nil {
+		return nil, err
+	}
+	var item bitwardenItem
+	if err := b.runJSON(&item, nil, "get", "item", id); err != nil {
+		return nil, fmt.Errorf("ошибка получения заметки %s: %v", id, err)
+	}
+	return &item, nil
+}
+
+// findItem ищет заметку по точному имени; nil, если не найдена
+func (b *BitwardenBackend) findItem(name string) (*bitwardenItem, error) {
+	if err := b.sync(); err != nil {
+		return nil, err
+	}
+	args := []string{"list", "items", "--search", name}
+	if bw := b.cfg.Bitwarden; bw != nil && bw.CollectionID != "" {
+		args = append(args, "--collectionid", bw.CollectionID)
+	}
+	var items []bitwardenItem
+	if err := b.runJSON(&items, nil, args...); err != nil {
+		return nil, fmt.Errorf("ошибка поиска заметки %s: %v", name, err)
+	}
+	for i := range items {
+		if items[i].Name == name {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// sync один раз за запуск подтягивает изменения с сервера
+func (b *BitwardenBackend) sync() error {
+	if b.synced {
+		return nil
+	}
+	if err := b.unlock(); err != nil {
+		return err
+	}
+	if _, err := b.run(nil, "sync"); err != nil {
+		return fmt.Errorf("ошибка синхронизации Bitwarden: %v", err)
+	}
+	b.synced = true
+	return nil
+}
+
+// unlock получает сессию: из BW_SESSION или интерактивно через bw unlock
+func (b *BitwardenBackend) unlock() error {
+	if b.session != "" {
+		return nil
+	}
+	status, err := b.status()
+	if err != nil {
+		return err
+	}
+	switch status.Status {
+	case "unlocked":
+		return nil
+	case "unauthenticated":
+		return fmt.Errorf("вы не вошли в Bitwarden: выполните bw login")
+	}
+
+	cmd := exec.Command(b.bin, "unlock", "--raw")
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("не удалось разблокировать хранилище Bitwarden: %v", err)
+	}
+	b.session = strings.TrimSpace(string(out))
+	return nil
+}
+
+type bitwardenStatus struct {
+	ServerURL string `json:"serverUrl"`
+	UserEmail string `json:"userEmail"`
+	Status    string `json:"status"`
+}
+
+func (b *BitwardenBackend) status() (*bitwardenStatus, error) {
+	var status bitwardenStatus
+	if err := b.runJSON(&status, nil, "status"); err != nil {
+		return nil, fmt.Errorf("ошибка вызова %s status: %v", b.bin, err)
+	}
+	return &status, nil
+}
+
+// run вызывает bw с сессией и возвращает stdout
+func (b *BitwardenBackend) run(stdin []byte, args ...string) ([]byte, error) {
+	if b.session != "" {
+		args = append(args, "--session", b.session)
+	}
+	cmd := exec.Command(b.bin, append(args, "--nointeraction")...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (b *BitwardenBackend) runJSON(out any, stdin []byte, args ...string) error {
+	data, err := b.run(stdin, args...)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func readBitwardenPointer(file string) (*bitwardenPointer, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var pointer bitwardenPointer
+	if err := yaml.Unmarshal(data, &pointer); err != nil || (pointer.ID == "" && pointer.Name == "") {
+		return nil, fmt.Errorf("файл %s не является указателем Bitwarden", file)
+	}
+	return &pointer, nil
+}
+
+func writeBitwardenPointer(file string, pointer bitwardenPointer) error {
+	data, err := yaml.Marshal(pointer)
+	if err != nil {
+		return err
+	}
+	header := "# Секрет хранится в Bitwarden. Восстановить: secret decrypt " + filepath.Base(file) + "\n"
+	return os.WriteFile(file, append([]byte(header), data...), 0644)
+}
+
+// Ключами шифрования управляет Bitwarden
+func (b *BitwardenBackend) GenerateKey(p KeyParams) (string, error) { return "", ErrNotSupported }
+
+func (b *BitwardenBackend) ExportKey(keyID string, secret bool) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (b *BitwardenBackend) ImportKey(path string) error { return ErrNotSupported }
+
+// Доступ к файлам определяется политиками сервиса
+func (b *BitwardenBackend) CanDecrypt(file string) error { return ErrNotSupported }
+
+func (b *BitwardenBackend) Seal(file string, plaintext []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (b *BitwardenBackend) Open(ciphertext []byte) ([]byte, error) { return nil, ErrNotSupported }
+
+func (b *BitwardenBackend) AddRecipient(ref string) ([]string, error) { return nil, ErrNotSupported }
+
+func (b *BitwardenBackend) Sign(keyID string, data []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (b *BitwardenBackend) Verify(data, signature, publicKey []byte) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (b *BitwardenBackend) FindKey(query string) (string, error) { return "", ErrNotSupported }
+
+func (b *BitwardenBackend) KeyInfo(keyID string) (*KeyInfo, error) { return nil, ErrNotSupported }
+
+func (b *BitwardenBackend) DeleteKey(keyID string) error { return ErrNotSupported }
+
+func (b *BitwardenBackend) ListKeys() (string, error) {
+	status, err := b.status()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Bitwarden: %s\nПользователь: %s\nСтатус: %s\n", status.ServerURL, status.UserEmail, status.Status), nil
+}
+
+func (b *BitwardenBackend) Check() error {
+	if _, err := exec.LookPath(b.bin); err != nil {
+		return fmt.Errorf("не найден CLI Bitwarden (%s): установите bw или задайте SECRET_BW_BIN", b.bin)
+	}
+	return b.unlock()
+}
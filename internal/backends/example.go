@@ -0,0 +1,69 @@
+package backends
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/fields"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// !TODO: вынести работу с .examples в отдельный модуль
+// writeExampleFile создает .example для файла по его содержимому в памяти.
+// Если заданы метки, плейсхолдерами заменяются только отмеченные значения.
+func writeExampleFile(originalFile string, content []byte, markers *config.Markers) error {
+	// Определяем тип файла по имени
+	format := fields.DetectFormat(originalFile)
+	values, err := fields.Scan(format, content)
+	var processed string
+	if err == nil {
+		processed = string(fields.Replace(content, values, func(v fields.Value, raw []byte) []byte {
+			if !markers.Marked(v.Key, v.Comment) {
+				return raw
+			}
+			return examplePlaceholder(format, raw)
+		}))
+	} else {
+		// Для неизвестных форматов просто создаем пустой файл
+		processed = "# Example file for " + filepath.Base(originalFile) + "\n"
+	}
+	return os.WriteFile(ExampleName(originalFile), []byte(processed), 0644)
+}
+
+// ExampleName возвращает имя .example-файла: config.yaml -> config.example.yaml
+func ExampleName(originalFile string) string {
+	ext := filepath.Ext(originalFile)
+	dir := filepath.Dir(originalFile)
+	fileBase := filepath.Base(originalFile)
+	baseWithoutExt := strings.TrimSuffix(fileBase, ext)
+	var exampleFileName string
+	if strings.HasPrefix(fileBase, ".") {
+		// Для скрытых файлов (.config.yaml) создаем .config.example.yaml
+		parts := strings.SplitN(fileBase, ".", 3)
+		if len(parts) >= 3 {
+			exampleFileName = strings.Join(parts[:2], ".") + ".example." + strings.Join(parts[2:], ".")
+		} else {
+			exampleFileName = baseWithoutExt + ".example" + ext
+		}
+	} else {
+		exampleFileName = baseWithoutExt + ".example" + ext
+	}
+	return filepath.Join(dir, exampleFileName)
+}
+
+// examplePlaceholder заменяет значение на <placeholder>, сохраняя кавычки.
+// В JSON и TOML числа, логические значения и массивы остаются как есть,
+// в YAML значение всегда записывается без кавычек.
+func examplePlaceholder(format fields.Format, raw []byte) []byte {
+	quoted := raw[0] == '"' || raw[0] == '\''
+	switch {
+	case format == fields.YAML:
+		return []byte("<placeholder>")
+	case quoted:
+		return []byte(string(raw[0]) + "<placeholder>" + string(raw[0]))
+	case format == fields.JSON || format == fields.TOML:
+		return raw
+	}
+	return []byte("<placeholder>")
+}
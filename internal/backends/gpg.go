@@ -1,16 +1,22 @@
 package backends
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/Avdushin/secret/pkg/config"
 )
 
+func init() {
+	Register("gpg", func(cfg *config.Config) (Backend, error) {
+		return NewGPGBackend(cfg), nil
+	})
+}
+
 type GPGBackend struct {
 	cfg *config.Config
 }
@@ -19,37 +25,67 @@ func NewGPGBackend(cfg *config.Config) *GPGBackend {
 	return &GPGBackend{cfg: cfg}
 }
 
+func (g *GPGBackend) Name() string { return "gpg" }
+
+func (g *GPGBackend) Ext() string { return ".gpg" }
+
 func (g *GPGBackend) Encrypt(file string) error {
-	if g.cfg.GPGKey == "" {
-		return fmt.Errorf("не настроен GPG-ключ проекта. Сначала выполните: secret init")
-	}
-	if _, err := os.Stat(file); os.IsNotExist(err) {
+	plaintext, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return err
 	}
-	outFile := file + ".gpg"
-	cmd := exec.Command(
-		"gpg",
-		"--encrypt",
-		"--recipient", g.cfg.GPGKey,
-		"--trust-model", "always",
-		"--output", outFile,
-		file,
-	)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ошибка шифрования: %v", err)
+	if err := g.EncryptBytes(file, plaintext); err != nil {
+		return err
+	}
+	outFile := file + g.Ext()
+	fmt.Printf("✅ Файл %s зашифрован в %s\n", file, outFile)
+	return nil
+}
+
+// EncryptBytes передает содержимое в gpg через stdin, не создавая открытых файлов
+func (g *GPGBackend) EncryptBytes(file string, plaintext []byte) error {
+	sealed, err := g.Seal(file, plaintext)
+	if err != nil {
+		return err
+	}
+	outFile := file + g.Ext()
+	if err := os.WriteFile(outFile, sealed, 0644); err != nil {
+		return err
 	}
 	// Создаем .example файл
-	if err := createExampleFile(file); err != nil {
+	if err := writeExampleFile(file, plaintext, g.cfg.Markers); err != nil {
 		return fmt.Errorf("не удалось создать .example файл: %v", err)
 	}
-	fmt.Printf("✅ Файл %s зашифрован в %s\n", file, outFile)
 	return nil
 }
 
+// Seal шифрует данные для получателей файла file через stdin/stdout gpg
+func (g *GPGBackend) Seal(file string, plaintext []byte) ([]byte, error) {
+	recipients, err := g.cfg.FileRecipients(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("не настроен GPG-ключ проекта. Сначала выполните: secret init")
+	}
+	args := []string{"--batch", "--yes", "--encrypt", "--trust-model", "always"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	cmd := exec.Command("gpg", append(args, "--output", "-")...)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	return out, nil
+}
+
 func (g *GPGBackend) Decrypt(file string) error {
-	if g.cfg.GPGKey == "" {
+	if len(g.cfg.EncryptionRecipients()) == 0 && len(g.cfg.Groups) == 0 {
 		return fmt.Errorf("не настроен GPG-ключ проекта")
 	}
 	if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -71,108 +107,374 @@ func (g *GPGBackend) Decrypt(file string) error {
 	return nil
 }
 
-// !TODO: вынести работу с .examples в отдельный модуль
-func createExampleFile(originalFile string) error {
-	content, err := os.ReadFile(originalFile)
+// DecryptBytes расшифровывает файл через stdout gpg, не создавая файлов
+func (g *GPGBackend) DecryptBytes(file string) ([]byte, error) {
+	if len(g.cfg.EncryptionRecipients()) == 0 && len(g.cfg.Groups) == 0 {
+		return nil, fmt.Errorf("не настроен GPG-ключ проекта")
+	}
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, fmt.Errorf("файл %s не существует", file)
+	}
+	cmd := exec.Command("gpg", "--quiet", "--decrypt", "--output", "-", file)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка дешифровки: %v", err)
+	}
+	return out, nil
+}
+
+// Open расшифровывает данные, переданные gpg через stdin
+func (g *GPGBackend) Open(ciphertext []byte) ([]byte, error) {
+	cmd := exec.Command("gpg", "--quiet", "--decrypt", "--output", "-")
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("ошибка дешифровки: %v", err)
+	}
+	return out, nil
+}
+
+func (g *GPGBackend) CanDecrypt(file string) error {
+	out, err := exec.Command("gpg", "--batch", "--list-only", "--list-packets", file).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "pubkey enc packet") {
+		return fmt.Errorf("не удалось прочитать %s: %v", file, err)
 	}
-	// Определяем тип файла по расширению
-	ext := filepath.Ext(originalFile)
-	var processed string
-	switch strings.ToLower(ext) {
-	case ".env":
-		processed = processEnvFile(string(content))
-	case ".json":
-		processed = processJSONFile(string(content))
-	case ".yaml", ".yml":
-		processed = processYAMLFile(string(content))
-	case ".toml":
-		processed = processTOMLFile(string(content))
-	case ".ini":
-		processed = processINIFile(string(content))
-	default:
-		// Для неизвестных форматов просто создаем пустой файл
-		processed = "# Example file for " + filepath.Base(originalFile) + "\n"
-	}
-	// Формируем правильное имя example-файла
-	dir := filepath.Dir(originalFile)
-	fileBase := filepath.Base(originalFile)
-	baseWithoutExt := strings.TrimSuffix(fileBase, ext)
-	var exampleFileName string
-	if strings.HasPrefix(fileBase, ".") {
-		// Для скрытых файлов (.config.yaml) создаем .config.example.yaml
-		parts := strings.SplitN(fileBase, ".", 3)
-		if len(parts) >= 3 {
-			exampleFileName = strings.Join(parts[:2], ".") + ".example." + strings.Join(parts[2:], ".")
-		} else {
-			exampleFileName = baseWithoutExt + ".example" + ext
+	var keyIDs []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if idx := strings.Index(line, "keyid "); strings.HasPrefix(line, ":pubkey enc packet:") && idx >= 0 {
+			keyIDs = append(keyIDs, strings.TrimSpace(line[idx+len("keyid "):]))
 		}
+	}
+
+	out, err = exec.Command("gpg", "--list-secret-keys", "--with-colons").Output()
+	if err != nil {
+		return fmt.Errorf("gpg error: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		if (fields[0] != "sec" && fields[0] != "ssb") || len(fields) < 5 {
+			continue
+		}
+		for _, id := range keyIDs {
+			if strings.EqualFold(fields[4], id) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("нет приватного ключа ни для одного из получателей (%s)", strings.Join(keyIDs, ", "))
+}
+
+func (g *GPGBackend) GenerateKey(p KeyParams) (string, error) {
+	// Создаем batch файл с выбранными параметрами
+	batchContent := fmt.Sprintf(`Key-Type: %s
+`, p.Type)
+
+	if p.Length > 0 {
+		batchContent += fmt.Sprintf("Key-Length: %d\n", p.Length)
+	}
+
+	if p.Type == "RSA" {
+		batchContent += `Subkey-Type: RSA
+Subkey-Length: ` + fmt.Sprintf("%d", p.Length) + `
+`
+	}
+
+	batchContent += fmt.Sprintf(`Name-Real: %s
+Name-Email: %s
+Expire-Date: %s
+`, p.Name, p.Email, p.Expire)
+
+	// gpg не принимает пустой Name-Comment
+	if p.Comment != "" {
+		batchContent += fmt.Sprintf("Name-Comment: %s\n", p.Comment)
+	}
+
+	if p.Passphrase != "" {
+		batchContent += fmt.Sprintf("Passphrase: %s\n", p.Passphrase)
 	} else {
-		exampleFileName = baseWithoutExt + ".example" + ext
+		batchContent += "%no-protection\n"
+	}
+
+	batchContent += "%commit\n"
+
+	// Создаем временный файл
+	tmpFile, err := os.CreateTemp("", "gpg-batch-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("не удалось создать временный файл: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(batchContent); err != nil {
+		return "", fmt.Errorf("не удалось записать во временный файл: %v", err)
+	}
+	tmpFile.Close()
+
+	// Выполняем команду создания ключа
+	cmd := exec.Command("gpg", "--batch", "--gen-key", tmpFile.Name())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", string(output), err)
+	}
+
+	// Ищем ID созданного ключа
+	cmd = exec.Command("gpg", "--list-secret-keys", "--keyid-format", "LONG", p.Email)
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		return "", err
 	}
-	exampleFile := filepath.Join(dir, exampleFileName)
-	return os.WriteFile(exampleFile, []byte(processed), 0644)
+
+	// Парсим ID ключа
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		if strings.Contains(line, "sec") {
+			parts := strings.Fields(line)
+			if len(parts) > 2 {
+				keyParts := strings.Split(parts[1], "/")
+				if len(keyParts) > 1 {
+					return keyParts[1], nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("не удалось определить ID созданного ключа")
+}
+
+func (g *GPGBackend) ExportKey(keyID string, secret bool) ([]byte, error) {
+	what, flag := "публичного", "--export"
+	if secret {
+		what, flag = "приватного", "--export-secret-keys"
+	}
+	cmd := exec.Command("gpg", "--armor", flag, keyID)
+	cmd.Stdin = os.Stdin // Для ввода пароля если нужно
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("экспорт %s ключа: %v", what, err)
+	}
+	if len(output) == 0 {
+		return nil, fmt.Errorf("экспорт %s ключа: ключ %s не найден", what, keyID)
+	}
+	return output, nil
 }
 
-// Улучшенная обработка YAML файлов
-func processYAMLFile(content string) string {
-	// Обрабатываем простые и многострочные значения, заменяем на <placeholder> без кавычек
-	re := regexp.MustCompile(`(?m)^(\s*[\w-]+\s*:\s*)(?:["'].*?['"]|\S+|>[^\n]*\n(?:\s+.*\n)*|\|[^\n]*\n(?:\s+.*\n)*)`)
-	processed := re.ReplaceAllString(content, `${1}<placeholder>`)
-	// Удаляем комментарии после значений
-	processed = regexp.MustCompile(`(?m)^(\s*[\w-]+\s*:\s*<placeholder>)\s*#.*$`).ReplaceAllString(processed, `${1}`)
-	return processed
+func (g *GPGBackend) ImportKey(path string) error {
+	cmd := exec.Command("gpg", "--import", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ошибка выполнения gpg --import: %v", err)
+	}
+
+	return nil
 }
 
-func processEnvFile(content string) string {
-	re := regexp.MustCompile(`(?m)^(\s*[\w-]+\s*=\s*)((?:"(.*?)")|(?:'(.*?)')|([^#\s]+))[ \t]*(.*)$`)
-	processed := re.ReplaceAllStringFunc(content, func(m string) string {
-		sub := re.FindStringSubmatch(m)
-		if len(sub) < 7 {
-			return m
+// AddRecipient импортирует публичный ключ участника из файла или
+// берет уже известный ключ по отпечатку и возвращает его fingerprint
+func (g *GPGBackend) AddRecipient(ref string) ([]string, error) {
+	if _, err := os.Stat(ref); err != nil {
+		info, err := g.KeyInfo(ref)
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s не найден: укажите файл с публичным ключом", ref)
 		}
-		prefix := sub[1]
-		rest := sub[6]
-		if sub[3] != "" {
-			return prefix + "\"<placeholder>\"" + rest
-		} else if sub[4] != "" {
-			return prefix + "'<placeholder>'" + rest
-		} else if sub[5] != "" {
-			return prefix + "<placeholder>" + rest
+		return []string{info.Fingerprint}, nil
+	}
+
+	// Смотрим содержимое файла, ничего не импортируя
+	out, err := exec.Command("gpg", "--with-colons", "--import-options", "show-only", "--import", ref).Output()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать ключ %s: %v", ref, err)
+	}
+	var fprs []string
+	primary := false
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		switch fields[0] {
+		case "sec":
+			return nil, fmt.Errorf("файл %s содержит приватный ключ: участнику нужно передать только публичный", ref)
+		case "pub":
+			primary = true
+		case "fpr":
+			if primary && len(fields) > 9 {
+				fprs = append(fprs, fields[9])
+			}
+			primary = false
 		}
-		return m
-	})
-	return processed
+	}
+	if len(fprs) == 0 {
+		return nil, fmt.Errorf("в файле %s не найдено публичных ключей", ref)
+	}
+	if err := g.ImportKey(ref); err != nil {
+		return nil, err
+	}
+	return fprs, nil
 }
 
-func processJSONFile(content string) string {
-	return regexp.MustCompile(`(?m)"([\w-]+)"\s*:\s*"(?:[^"\\]|\\.)*"`).
-		ReplaceAllString(content, `"${1}": "<placeholder>"`)
+func (g *GPGBackend) Sign(keyID string, data []byte) ([]byte, error) {
+	cmd := exec.Command("gpg", "--armor", "--detach-sign", "--local-user", keyID, "--output", "-")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подписи ключом %s: %v", keyID, err)
+	}
+	return out, nil
 }
 
-func processTOMLFile(content string) string {
-	return regexp.MustCompile(`(?m)^\s*([\w-]+)\s*=\s*"(?:[^"\\]|\\.)*"`).
-		ReplaceAllString(content, `${1} = "<placeholder>"`)
+// Verify не трогает связку gpg: подпись проверяется встроенной реализацией OpenPGP
+func (g *GPGBackend) Verify(data, signature, publicKey []byte) (string, error) {
+	return verifyDetached(data, signature, publicKey)
 }
 
-func processINIFile(content string) string {
-	re := regexp.MustCompile(`(?m)^(\s*[\w-]+\s*=\s*)((?:"(.*?)")|(?:'(.*?)')|([^#\s]+))[ \t]*(.*)$`)
-	processed := re.ReplaceAllStringFunc(content, func(m string) string {
-		sub := re.FindStringSubmatch(m)
-		if len(sub) < 7 {
-			return m
+func (g *GPGBackend) FindKey(query string) (string, error) {
+	out, err := exec.Command("gpg", "--list-secret-keys", "--keyid-format=LONG").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("gpg error: %v", err)
+	}
+
+	lines := strings.Split(string(out), "\n")
+	for idx, line := range lines {
+		if query == "" && strings.HasPrefix(line, "sec") {
+			if keyID := parseSecLine(line); keyID != "" {
+				return keyID, nil
+			}
+			continue
 		}
-		prefix := sub[1]
-		rest := sub[6]
-		if sub[3] != "" {
-			return prefix + "\"<placeholder>\"" + rest
-		} else if sub[4] != "" {
-			return prefix + "'<placeholder>'" + rest
-		} else if sub[5] != "" {
-			return prefix + "<placeholder>" + rest
+		if query != "" && strings.Contains(line, "uid") && strings.Contains(line, query) {
+			// Ищем "sec" в предыдущих строках (назад до 5 строк)
+			for j := 1; j <= 5; j++ {
+				if idx-j < 0 {
+					break
+				}
+				prevLine := lines[idx-j]
+				if strings.Contains(prevLine, "sec") {
+					if keyID := parseSecLine(prevLine); keyID != "" {
+						return keyID, nil
+					}
+				}
+			}
 		}
-		return m
-	})
-	return processed
+	}
+	if query == "" {
+		return "", fmt.Errorf("не удалось автоматически определить ключ")
+	}
+	return "", fmt.Errorf("не удалось найти ключ для проекта %s", query)
+}
+
+// parseSecLine достает ID ключа из строки вида "sec   rsa4096/ABCDEF1234567890 ..."
+func parseSecLine(line string) string {
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return ""
+	}
+	keyParts := strings.Split(parts[1], "/")
+	if len(keyParts) != 2 {
+		return ""
+	}
+	return keyParts[1]
+}
+
+func (g *GPGBackend) KeyInfo(keyID string) (*KeyInfo, error) {
+	cmd := exec.Command("gpg", "--list-keys", "--with-colons", keyID)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ключ %s не найден в GPG: %v", keyID, err)
+	}
+
+	info := &KeyInfo{ID: keyID}
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		parts := strings.Split(line, ":")
+		if len(parts) <= 9 {
+			continue
+		}
+		switch parts[0] {
+		case "fpr":
+			// fpr:::::::::FINGERPRINT: — первый fpr принадлежит основному ключу
+			if info.Fingerprint == "" {
+				info.Fingerprint = parts[9]
+			}
+		case "uid":
+			if info.Name == "" {
+				info.Name = parts[9]
+				info.Email = extractEmail(parts[9])
+			}
+		}
+	}
+
+	if info.Fingerprint == "" {
+		return nil, fmt.Errorf("не удалось найти fingerprint для ключа %s", keyID)
+	}
+	return info, nil
+}
+
+func extractEmail(uid string) string {
+	start := strings.Index(uid, "<")
+	end := strings.Index(uid, ">")
+	if start >= 0 && end > start {
+		return uid[start+1 : end]
+	}
+	return ""
+}
+
+func (g *GPGBackend) DeleteKey(keyID string) error {
+	// Удаляем приватный ключ в batch режиме
+	if err := runGPGDelete("--delete-secret-keys", keyID); err != nil {
+		return fmt.Errorf("не удалось удалить приватный ключ: %v", err)
+	}
+	// Удаляем публичный ключ в batch режиме
+	if err := runGPGDelete("--delete-keys", keyID); err != nil {
+		return fmt.Errorf("не удалось удалить публичный ключ: %v", err)
+	}
+	return nil
+}
+
+func runGPGDelete(flag, fingerprint string) error {
+	cmd := exec.Command("gpg", "--batch", "--yes", flag, fingerprint)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err == nil {
+		return nil
+	}
+
+	// Если batch не сработал, пробуем интерактивно
+	fmt.Println("⚠️  Не удалось удалить ключ в batch режиме, пробуем интерактивно...")
+	cmd = exec.Command("gpg", flag, fingerprint)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (g *GPGBackend) ListKeys() (string, error) {
+	out, err := exec.Command("gpg", "--list-secret-keys", "--keyid-format=LONG").CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (g *GPGBackend) Check() error {
+	recipients := g.cfg.EncryptionRecipients()
+	if len(recipients) == 0 {
+		return fmt.Errorf("не настроен GPG-ключ проекта")
+	}
+	args := []string{"--batch", "--yes", "--encrypt", "--trust-model", "always", "--armor", "--output", "/dev/null"}
+	for _, r := range recipients {
+		// Публичные ключи всех получателей должны быть в связке
+		if _, err := g.KeyInfo(r); err != nil {
+			return fmt.Errorf("нет публичного ключа получателя %s: %v", r, err)
+		}
+		args = append(args, "--recipient", r)
+	}
+	cmd := exec.Command("gpg", append(args, "/dev/null")...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ошибка тестового шифрования: %v", err)
+	}
+	return nil
 }
@@ -0,0 +1,640 @@
+package backends
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Avdushin/secret/pkg/config"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func init() {
+	Register("openpgp", func(cfg *config.Config) (Backend, error) {
+		return NewOpenPGPBackend(cfg)
+	})
+}
+
+// OpenPGPBackend шифрует файлы в процессе, без вызова gpg.
+// Ключи хранятся в собственной связке (см. openpgpHome), а зашифрованные
+// файлы совместимы с gpg: их можно расшифровать через gpg --decrypt и наоборот.
+type OpenPGPBackend struct {
+	cfg  *config.Config
+	home string
+}
+
+func NewOpenPGPBackend(cfg *config.Config) (*OpenPGPBackend, error) {
+	home, err := openpgpHome()
+	if err != nil {
+		return nil, err
+	}
+	return &OpenPGPBackend{cfg: cfg, home: home}, nil
+}
+
+// openpgpHome возвращает директорию связки ключей.
+// Переопределяется переменной окружения SECRET_OPENPGP_HOME.
+func openpgpHome() (string, error) {
+	if dir := os.Getenv("SECRET_OPENPGP_HOME"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("не удалось определить директорию конфигурации: %v", err)
+	}
+	return filepath.Join(dir, "secret", "openpgp"), nil
+}
+
+func (o *OpenPGPBackend) Name() string { return "openpgp" }
+
+func (o *OpenPGPBackend) Ext() string { return ".gpg" }
+
+func (o *OpenPGPBackend) Encrypt(file string) error {
+	plaintext, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return err
+	}
+	if err := o.EncryptBytes(file, plaintext); err != nil {
+		return err
+	}
+	outFile := file + o.Ext()
+	fmt.Printf("✅ Файл %s зашифрован в %s\n", file, outFile)
+	return nil
+}
+
+// EncryptBytes шифрует содержимое для файла file, не читая его с диска
+func (o *OpenPGPBackend) EncryptBytes(file string, plaintext []byte) error {
+	sealed, err := o.Seal(file, plaintext)
+	if err != nil {
+		return err
+	}
+	outFile := file + o.Ext()
+	if err := os.WriteFile(outFile, sealed, 0644); err != nil {
+		return err
+	}
+	// Создаем .example файл
+	if err := writeExampleFile(file, plaintext, o.cfg.Markers); err != nil {
+		return fmt.Errorf("не удалось создать .example файл: %v", err)
+	}
+	return nil
+}
+
+// Seal шифрует данные для получателей файла file
+func (o *OpenPGPBackend) Seal(file string, plaintext []byte) ([]byte, error) {
+	recipients, err := o.cfg.FileRecipients(file)
+	if err != nil {
+		return nil, err
+	}
+	to, err := o.recipientEntities(recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	hints := &openpgp.FileHints{IsBinary: true, FileName: filepath.Base(file)}
+	w, err := openpgp.Encrypt(&buf, to, nil, hints, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// recipientEntities возвращает ключи получателей из связки
+func (o *OpenPGPBackend) recipientEntities(recipients []string) ([]*openpgp.Entity, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("не настроен ключ проекта. Сначала выполните: secret init")
+	}
+	keyring, err := o.keyring()
+	if err != nil {
+		return nil, err
+	}
+	to := make([]*openpgp.Entity, 0, len(recipients))
+	for _, r := range recipients {
+		entity := findEntity(keyring, r)
+		if entity == nil {
+			return nil, fmt.Errorf("ключ получателя %s не найден в связке %s", r, o.home)
+		}
+		to = append(to, entity)
+	}
+	return to, nil
+}
+
+func (o *OpenPGPBackend) Decrypt(file string) error {
+	plaintext, err := o.DecryptBytes(file)
+	if err != nil {
+		return err
+	}
+
+	outFile := strings.TrimSuffix(file, filepath.Ext(file))
+	if err := os.WriteFile(outFile, plaintext, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s расшифрован в %s\n", file, outFile)
+	return nil
+}
+
+func (o *OpenPGPBackend) DecryptBytes(file string) ([]byte, error) {
+	if len(o.cfg.EncryptionRecipients()) == 0 && len(o.cfg.Groups) == 0 {
+		return nil, fmt.Errorf("не настроен ключ проекта")
+	}
+	ciphertext, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return nil, err
+	}
+	return o.Open(ciphertext)
+}
+
+// Open расшифровывает данные ключами из связки
+func (o *OpenPGPBackend) Open(ciphertext []byte) ([]byte, error) {
+	keyring, err := o.keyring()
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := openpgp.ReadMessage(unarmor(bytes.NewReader(ciphertext)), keyring, promptKeyPassphrase, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка дешифровки: %v", err)
+	}
+	plaintext, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка дешифровки: %v", err)
+	}
+	return plaintext, nil
+}
+
+// CanDecrypt сверяет ID ключей из заголовка файла с приватными ключами связки
+func (o *OpenPGPBackend) CanDecrypt(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var keyIDs []uint64
+	packets := packet.NewReader(unarmor(f))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			break
+		}
+		ek, ok := p.(*packet.EncryptedKey)
+		if !ok {
+			break
+		}
+		keyIDs = append(keyIDs, ek.KeyId)
+	}
+
+	keyring, err := o.keyring()
+	if err != nil {
+		return err
+	}
+	for _, id := range keyIDs {
+		for _, key := range keyring.KeysById(id) {
+			if key.PrivateKey != nil {
+				return nil
+			}
+		}
+	}
+	ids := make([]string, len(keyIDs))
+	for i, id := range keyIDs {
+		ids[i] = fmt.Sprintf("%016X", id)
+	}
+	return fmt.Errorf("нет приватного ключа ни для одного из получателей (%s)", strings.Join(ids, ", "))
+}
+
+// unarmor прозрачно снимает ASCII-armor, если он есть
+func unarmor(r io.Reader) io.Reader {
+	var buf bytes.Buffer
+	tee := io.TeeReader(r, &buf)
+	if block, err := armor.Decode(tee); err == nil {
+		return block.Body
+	}
+	return io.MultiReader(&buf, r)
+}
+
+// promptKeyPassphrase расшифровывает защищенные паролем приватные ключи
+func promptKeyPassphrase(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+	if symmetric {
+		return nil, fmt.Errorf("симметрично зашифрованные файлы не поддерживаются")
+	}
+	for _, k := range keys {
+		if k.PrivateKey == nil || !k.PrivateKey.Encrypted {
+			continue
+		}
+		passphrase, err := readPassphrase(fmt.Sprintf("Парольная фраза для ключа %X: ", k.PublicKey.KeyId))
+		if err != nil {
+			return nil, err
+		}
+		if err := k.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("неверная парольная фраза: %v", err)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("нет подходящего приватного ключа")
+}
+
+func (o *OpenPGPBackend) GenerateKey(p KeyParams) (string, error) {
+	cfg := &packet.Config{}
+	switch p.Type {
+	case "RSA":
+		cfg.Algorithm = packet.PubKeyAlgoRSA
+		cfg.RSABits = p.Length
+	case "ECC":
+		cfg.Algorithm = packet.PubKeyAlgoEdDSA
+		cfg.Curve = packet.Curve25519
+	default:
+		return "", fmt.Errorf("тип ключа %s не поддерживается бэкендом openpgp (доступны: RSA, ECC)", p.Type)
+	}
+
+	lifetime, err := parseExpire(p.Expire)
+	if err != nil {
+		return "", err
+	}
+	cfg.KeyLifetimeSecs = uint32(lifetime.Seconds())
+
+	entity, err := openpgp.NewEntity(p.Name, p.Comment, p.Email, cfg)
+	if err != nil {
+		return "", fmt.Errorf("ошибка генерации ключа: %v", err)
+	}
+	if p.Passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(p.Passphrase), cfg); err != nil {
+			return "", fmt.Errorf("ошибка защиты ключа паролем: %v", err)
+		}
+	}
+	if err := o.saveEntity(entity); err != nil {
+		return "", err
+	}
+	return keyIDString(entity), nil
+}
+
+// parseExpire разбирает срок действия в формате gpg: 0, 2y, 12m, 52w, 355 (дней)
+func parseExpire(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	unit := time.Duration(24) * time.Hour
+	switch s[len(s)-1] {
+	case 'y':
+		unit *= 365
+		s = s[:len(s)-1]
+	case 'm':
+		unit *= 30
+		s = s[:len(s)-1]
+	case 'w':
+		unit *= 7
+		s = s[:len(s)-1]
+	case 'd':
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("неверный срок действия ключа: %s", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func (o *OpenPGPBackend) ExportKey(keyID string, secret bool) ([]byte, error) {
+	keyring, err := o.keyring()
+	if err != nil {
+		return nil, err
+	}
+	entity := findEntity(keyring, keyID)
+	if entity == nil {
+		return nil, fmt.Errorf("ключ %s не найден", keyID)
+	}
+	if secret && entity.PrivateKey == nil {
+		return nil, fmt.Errorf("приватный ключ %s отсутствует в связке", keyID)
+	}
+	return armorEntity(entity, secret)
+}
+
+func armorEntity(entity *openpgp.Entity, secret bool) ([]byte, error) {
+	var buf bytes.Buffer
+	blockType := openpgp.PublicKeyType
+	if secret {
+		blockType = openpgp.PrivateKeyType
+	}
+	w, err := armor.Encode(&buf, blockType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if secret {
+		err = entity.SerializePrivateWithoutSigning(w, nil)
+	} else {
+		err = entity.Serialize(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *OpenPGPBackend) ImportKey(path string) error {
+	entities, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+	return o.importEntities(entities)
+}
+
+// readKeyFile читает ключи в armored или бинарном виде
+func readKeyFile(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать ключ %s: %v", path, err)
+	}
+	return entities, nil
+}
+
+func (o *OpenPGPBackend) importEntities(entities openpgp.EntityList) error {
+	for _, entity := range entities {
+		// Не затираем приватный ключ публичным при повторном импорте
+		if entity.PrivateKey == nil {
+			if existing, err := o.loadEntity(o.entityPath(entity)); err == nil && existing.PrivateKey != nil {
+				continue
+			}
+		}
+		if err := o.saveEntity(entity); err != nil {
+			return err
+		}
+		fmt.Printf("🔑 Импортирован ключ %s\n", keyIDString(entity))
+	}
+	return nil
+}
+
+// AddRecipient импортирует публичный ключ участника из файла или
+// берет уже известный ключ по отпечатку и возвращает его fingerprint
+func (o *OpenPGPBackend) AddRecipient(ref string) ([]string, error) {
+	if _, err := os.Stat(ref); err != nil {
+		info, err := o.KeyInfo(ref)
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s не найден: укажите файл с публичным ключом", ref)
+		}
+		return []string{info.Fingerprint}, nil
+	}
+
+	entities, err := readKeyFile(ref)
+	if err != nil {
+		return nil, err
+	}
+	fprs := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		if entity.PrivateKey != nil {
+			return nil, fmt.Errorf("файл %s содержит приватный ключ: участнику нужно передать только публичный", ref)
+		}
+		fprs = append(fprs, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint))
+	}
+	if err := o.importEntities(entities); err != nil {
+		return nil, err
+	}
+	return fprs, nil
+}
+
+func (o *OpenPGPBackend) Sign(keyID string, data []byte) ([]byte, error) {
+	keyring, err := o.keyring()
+	if err != nil {
+		return nil, err
+	}
+	entity := findEntity(keyring, keyID)
+	if entity == nil || entity.PrivateKey == nil {
+		return nil, fmt.Errorf("приватный ключ %s не найден в связке %s", keyID, o.home)
+	}
+	if entity.PrivateKey.Encrypted {
+		passphrase, err := readPassphrase(fmt.Sprintf("Парольная фраза для ключа %s: ", keyIDString(entity)))
+		if err != nil {
+			return nil, err
+		}
+		if err := entity.DecryptPrivateKeys(passphrase); err != nil {
+			return nil, fmt.Errorf("неверная парольная фраза: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(data), nil); err != nil {
+		return nil, fmt.Errorf("ошибка подписи: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *OpenPGPBackend) Verify(data, signature, publicKey []byte) (string, error) {
+	return verifyDetached(data, signature, publicKey)
+}
+
+// verifyDetached проверяет armored-подпись данных публичным ключом
+func verifyDetached(data, signature, publicKey []byte) (string, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать публичный ключ: %v", err)
+	}
+	// Лишние ключи в блоке стали бы получателями без проверки владельцем
+	if len(keyring) != 1 {
+		return "", fmt.Errorf("блок публичного ключа должен содержать один ключ, а содержит %d", len(keyring))
+	}
+	signer, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(signature), nil)
+	if err != nil {
+		return "", fmt.Errorf("подпись недействительна: %v", err)
+	}
+	return fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint), nil
+}
+
+func (o *OpenPGPBackend) FindKey(query string) (string, error) {
+	keyring, err := o.keyring()
+	if err != nil {
+		return "", err
+	}
+	for _, entity := range keyring {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if query == "" {
+			return keyIDString(entity), nil
+		}
+		for uid := range entity.Identities {
+			if strings.Contains(uid, query) {
+				return keyIDString(entity), nil
+			}
+		}
+	}
+	if query == "" {
+		return "", fmt.Errorf("не удалось автоматически определить ключ")
+	}
+	return "", fmt.Errorf("не удалось найти ключ для проекта %s", query)
+}
+
+func (o *OpenPGPBackend) KeyInfo(keyID string) (*KeyInfo, error) {
+	keyring, err := o.keyring()
+	if err != nil {
+		return nil, err
+	}
+	entity := findEntity(keyring, keyID)
+	if entity == nil {
+		return nil, fmt.Errorf("ключ %s не найден в связке %s", keyID, o.home)
+	}
+	info := &KeyInfo{
+		ID:          keyIDString(entity),
+		Fingerprint: fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint),
+	}
+	if ident := entity.PrimaryIdentity(); ident != nil {
+		info.Name = ident.Name
+		info.Email = ident.UserId.Email
+	}
+	return info, nil
+}
+
+func (o *OpenPGPBackend) DeleteKey(keyID string) error {
+	keyring, err := o.keyring()
+	if err != nil {
+		return err
+	}
+	entity := findEntity(keyring, keyID)
+	if entity == nil {
+		return fmt.Errorf("ключ %s не найден", keyID)
+	}
+	return os.Remove(o.entityPath(entity))
+}
+
+func (o *OpenPGPBackend) ListKeys() (string, error) {
+	keyring, err := o.keyring()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n", o.home)
+	for _, entity := range keyring {
+		kind := "pub"
+		if entity.PrivateKey != nil {
+			kind = "sec"
+		}
+		fmt.Fprintf(&sb, "%s   %s/%s %s\n", kind, algoName(entity.PrimaryKey),
+			keyIDString(entity), entity.PrimaryKey.CreationTime.Format("2006-01-02"))
+		fmt.Fprintf(&sb, "      %X\n", entity.PrimaryKey.Fingerprint)
+		for uid := range entity.Identities {
+			fmt.Fprintf(&sb, "uid   %s\n", uid)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+func (o *OpenPGPBackend) Check() error {
+	to, err := o.recipientEntities(o.cfg.EncryptionRecipients())
+	if err != nil {
+		return err
+	}
+	w, err := openpgp.Encrypt(io.Discard, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка тестового шифрования: %v", err)
+	}
+	return w.Close()
+}
+
+// algoName возвращает название алгоритма ключа в стиле вывода gpg
+func algoName(key *packet.PublicKey) string {
+	switch key.PubKeyAlgo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		bits, _ := key.BitLength()
+		return fmt.Sprintf("rsa%d", bits)
+	case packet.PubKeyAlgoEdDSA, packet.PubKeyAlgoEd25519:
+		return "ed25519"
+	case packet.PubKeyAlgoECDSA:
+		return "ecdsa"
+	case packet.PubKeyAlgoDSA:
+		return "dsa"
+	default:
+		return fmt.Sprintf("algo%d", key.PubKeyAlgo)
+	}
+}
+
+// keyring читает все ключи из связки
+func (o *OpenPGPBackend) keyring() (openpgp.EntityList, error) {
+	files, err := filepath.Glob(filepath.Join(o.home, "*.asc"))
+	if err != nil {
+		return nil, err
+	}
+	var keyring openpgp.EntityList
+	for _, file := range files {
+		entity, err := o.loadEntity(file)
+		if err != nil {
+			return nil, err
+		}
+		keyring = append(keyring, entity)
+	}
+	return keyring, nil
+}
+
+func (o *OpenPGPBackend) loadEntity(path string) (*openpgp.Entity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("повреждён ключ %s: %v", path, err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("файл %s должен содержать ровно один ключ", path)
+	}
+	return entities[0], nil
+}
+
+func (o *OpenPGPBackend) saveEntity(entity *openpgp.Entity) error {
+	if err := os.MkdirAll(o.home, 0700); err != nil {
+		return err
+	}
+	data, err := armorEntity(entity, entity.PrivateKey != nil)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации ключа: %v", err)
+	}
+	return os.WriteFile(o.entityPath(entity), data, 0600)
+}
+
+func (o *OpenPGPBackend) entityPath(entity *openpgp.Entity) string {
+	return filepath.Join(o.home, fmt.Sprintf("%X.asc", entity.PrimaryKey.Fingerprint))
+}
+
+// keyIDString возвращает длинный ID ключа, как его показывает gpg
+func keyIDString(entity *openpgp.Entity) string {
+	return fmt.Sprintf("%016X", entity.PrimaryKey.KeyId)
+}
+
+// findEntity ищет ключ по длинному ID, короткому ID или fingerprint
+func findEntity(keyring openpgp.EntityList, keyID string) *openpgp.Entity {
+	keyID = strings.ToUpper(strings.TrimPrefix(keyID, "0x"))
+	if keyID == "" {
+		return nil
+	}
+	for _, entity := range keyring {
+		if strings.HasSuffix(fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), keyID) {
+			return entity
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package backends
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// readPassphrase запрашивает парольную фразу без эха.
+// Для неинтерактивного режима (CI) используется переменная SECRET_PASSPHRASE.
+func readPassphrase(prompt string) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv("SECRET_PASSPHRASE"); ok {
+		return []byte(passphrase), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return passphrase, err
+	}
+
+	// Fallback: обычный ввод
+	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && input == "" {
+		return nil, fmt.Errorf("не удалось прочитать парольную фразу: %v", err)
+	}
+	return []byte(strings.TrimSpace(input)), nil
+}
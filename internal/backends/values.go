@@ -0,0 +1,373 @@
+package backends
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/fields"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// valuesBackend реализует mode: values. Каждое значение .env, INI, JSON,
+// YAML и TOML файла шифруется отдельно (AES-256-GCM общим ключом данных),
+// а ключи и комментарии остаются открытыми, поэтому в git diff видно,
+// какой ключ изменился. Ключ данных шифруется основным бэкендом для
+// получателей файла и хранится в заголовке вместе с MAC всего документа.
+// Файлы других форматов основной бэкенд шифрует целиком.
+// Если в конфиге заданы markers, шифруются только отмеченные значения,
+// а MAC покрывает только их, чтобы остальное можно было править вручную.
+type valuesBackend struct {
+	Backend
+	cfg *config.Config
+}
+
+// valuesHeader — заголовок файла с зашифрованными значениями
+type valuesHeader struct {
+	DataKey    string // ключ данных, зашифрованный основным бэкендом (base64)
+	Recipients string // получатели, для которых зашифрован ключ данных
+	MAC        string // HMAC-SHA256 открытого документа или отмеченных значений
+}
+
+// markedMAC — префикс MAC, покрывающего только зашифрованные значения
+const markedMAC = "values:"
+
+var (
+	lineHeader = regexp.MustCompile(`\A# secret:data_key=(\S*)\n# secret:recipients=(.*)\n# secret:mac=(\S*)\n`)
+	jsonHeader = regexp.MustCompile(`\A(\s*\{)\n  "secret:data_key": "([^"]*)",\n  "secret:recipients": "([^"]*)",\n  "secret:mac": "([^"]*)",`)
+	encValue   = regexp.MustCompile(`^"?ENC\[AES256_GCM,data:([A-Za-z0-9+/=]*),iv:([A-Za-z0-9+/=]+)\]"?$`)
+)
+
+func (v *valuesBackend) Encrypt(file string) error {
+	plaintext, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return err
+	}
+	if err := v.EncryptBytes(file, plaintext); err != nil {
+		return err
+	}
+	outFile := file + v.Ext()
+	fmt.Printf("✅ Файл %s зашифрован в %s\n", file, outFile)
+	return nil
+}
+
+func (v *valuesBackend) EncryptBytes(file string, plaintext []byte) error {
+	format := fields.DetectFormat(file)
+	if format == fields.Unknown || (format == fields.JSON && !isJSONObject(plaintext)) {
+		return v.Backend.EncryptBytes(file, plaintext)
+	}
+	values, err := fields.Scan(format, plaintext)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if len(values) == 0 {
+		return v.Backend.EncryptBytes(file, plaintext)
+	}
+
+	recipients, err := v.cfg.FileRecipients(file)
+	if err != nil {
+		return err
+	}
+	dataKey, sealedKey, err := v.dataKey(file, recipients)
+	if err != nil {
+		return err
+	}
+	keys, err := deriveValueKeys(dataKey)
+	if err != nil {
+		return err
+	}
+
+	marked := values[:0:0]
+	for _, val := range values {
+		if v.cfg.Markers.Marked(val.Key, val.Comment) {
+			marked = append(marked, val)
+		}
+	}
+
+	// В JSON и TOML шифротекст записывается строкой
+	quote := format == fields.JSON || format == fields.TOML
+	var sealed bytes.Buffer
+	body := fields.Replace(plaintext, marked, func(val fields.Value, raw []byte) []byte {
+		writeMACValue(&sealed, val.Key, raw)
+		token := keys.seal(val.Key, raw)
+		if quote {
+			token = `"` + token + `"`
+		}
+		return []byte(token)
+	})
+	header := valuesHeader{
+		DataKey:    base64.StdEncoding.EncodeToString(sealedKey),
+		Recipients: strings.Join(recipients, ","),
+		MAC:        keys.sum(plaintext),
+	}
+	if v.cfg.Markers != nil {
+		header.MAC = markedMAC + keys.sum(sealed.Bytes())
+	}
+
+	outFile := file + v.Ext()
+	if err := os.WriteFile(outFile, header.prepend(format, body), 0644); err != nil {
+		return err
+	}
+	// Создаем .example файл
+	if err := writeExampleFile(file, plaintext, v.cfg.Markers); err != nil {
+		return fmt.Errorf("не удалось создать .example файл: %v", err)
+	}
+	return nil
+}
+
+func (v *valuesBackend) Decrypt(file string) error {
+	plaintext, err := v.DecryptBytes(file)
+	if err != nil {
+		return err
+	}
+
+	outFile := strings.TrimSuffix(file, filepath.Ext(file))
+	if err := os.WriteFile(outFile, plaintext, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s расшифрован в %s\n", file, outFile)
+	return nil
+}
+
+// DecryptBytes расшифровывает значения и проверяет MAC документа.
+// Файлы, зашифрованные целиком, расшифровывает основной бэкенд.
+func (v *valuesBackend) DecryptBytes(file string) ([]byte, error) {
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return nil, err
+	}
+	format := fields.DetectFormat(strings.TrimSuffix(file, v.Ext()))
+	header, body, ok := splitValuesHeader(format, content)
+	if !ok {
+		return v.Backend.DecryptBytes(file)
+	}
+
+	sealedKey, err := base64.StdEncoding.DecodeString(header.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("поврежден ключ данных в %s: %v", file, err)
+	}
+	dataKey, err := v.Backend.Open(sealedKey)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := deriveValueKeys(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := fields.Scan(format, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	var openErr error
+	var sealed bytes.Buffer
+	plaintext := fields.Replace(body, values, func(val fields.Value, raw []byte) []byte {
+		m := encValue.FindSubmatch(raw)
+		if m == nil {
+			// Значение без метки хранится открытым
+			return raw
+		}
+		value, err := keys.open(val.Key, string(m[1]), string(m[2]))
+		if err != nil && openErr == nil {
+			openErr = fmt.Errorf("не удалось расшифровать значение %s: %v", val.Key, err)
+		}
+		writeMACValue(&sealed, val.Key, value)
+		return value
+	})
+	if openErr != nil {
+		return nil, openErr
+	}
+	mac := keys.sum(plaintext)
+	if strings.HasPrefix(header.MAC, markedMAC) {
+		mac = markedMAC + keys.sum(sealed.Bytes())
+	}
+	if !hmac.Equal([]byte(mac), []byte(header.MAC)) {
+		return nil, fmt.Errorf("MAC файла %s не совпадает: файл изменен в обход secret", file)
+	}
+	return plaintext, nil
+}
+
+// CanDecrypt проверяет доступ к ключу данных из заголовка
+func (v *valuesBackend) CanDecrypt(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	header, _, ok := splitValuesHeader(fields.DetectFormat(strings.TrimSuffix(file, v.Ext())), content)
+	if !ok {
+		return v.Backend.CanDecrypt(file)
+	}
+	sealedKey, err := base64.StdEncoding.DecodeString(header.DataKey)
+	if err != nil {
+		return fmt.Errorf("поврежден ключ данных в %s: %v", file, err)
+	}
+
+	// Основной бэкенд проверяет доступ по файлу
+	tmp, err := os.CreateTemp("", "secret-key-*"+v.Ext())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(sealedKey)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return v.Backend.CanDecrypt(tmp.Name())
+}
+
+// dataKey возвращает ключ данных из уже зашифрованного файла, если состав
+// получателей не изменился: тогда у неизмененных значений остается прежний
+// шифротекст. Иначе создается новый ключ.
+func (v *valuesBackend) dataKey(file string, recipients []string) ([]byte, []byte, error) {
+	if existing, err := os.ReadFile(file + v.Ext()); err == nil {
+		header, _, ok := splitValuesHeader(fields.DetectFormat(file), existing)
+		if ok && header.Recipients == strings.Join(recipients, ",") {
+			if sealedKey, err := base64.StdEncoding.DecodeString(header.DataKey); err == nil {
+				if key, err := v.Backend.Open(sealedKey); err == nil && len(key) == 32 {
+					return key, sealedKey, nil
+				}
+			}
+		}
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	sealedKey, err := v.Backend.Seal(file, key)
+	if errors.Is(err, ErrNotSupported) {
+		return nil, nil, fmt.Errorf("бэкенд %s не поддерживает mode: %s", v.Name(), config.ModeValues)
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return key, sealedKey, nil
+}
+
+// prepend добавляет заголовок в начало документа: комментариями
+// или, для JSON, служебными ключами в начале корневого объекта
+func (h valuesHeader) prepend(format fields.Format, body []byte) []byte {
+	var buf bytes.Buffer
+	if format == fields.JSON {
+		i := bytes.IndexByte(body, '{') + 1
+		buf.Write(body[:i])
+		fmt.Fprintf(&buf, "\n  \"secret:data_key\": %q,\n  \"secret:recipients\": %q,\n  \"secret:mac\": %q,", h.DataKey, h.Recipients, h.MAC)
+		buf.Write(body[i:])
+		return buf.Bytes()
+	}
+	fmt.Fprintf(&buf, "# secret:data_key=%s\n# secret:recipients=%s\n# secret:mac=%s\n", h.DataKey, h.Recipients, h.MAC)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// splitValuesHeader отделяет заголовок от документа. ok=false означает,
+// что файл зашифрован целиком.
+func splitValuesHeader(format fields.Format, content []byte) (valuesHeader, []byte, bool) {
+	if format == fields.JSON {
+		m := jsonHeader.FindSubmatchIndex(content)
+		if m == nil {
+			return valuesHeader{}, nil, false
+		}
+		header := valuesHeader{
+			DataKey:    string(content[m[4]:m[5]]),
+			Recipients: string(content[m[6]:m[7]]),
+			MAC:        string(content[m[8]:m[9]]),
+		}
+		body := append(append([]byte{}, content[m[2]:m[3]]...), content[m[1]:]...)
+		return header, body, true
+	}
+
+	m := lineHeader.FindSubmatch(content)
+	if m == nil {
+		return valuesHeader{}, nil, false
+	}
+	header := valuesHeader{DataKey: string(m[1]), Recipients: string(m[2]), MAC: string(m[3])}
+	return header, content[len(m[0]):], true
+}
+
+func isJSONObject(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// valueKeys — ключи, производные от ключа данных
+type valueKeys struct {
+	aead  cipher.AEAD
+	ivKey []byte
+	mac   []byte
+}
+
+func deriveValueKeys(dataKey []byte) (*valueKeys, error) {
+	derive := func(label string) []byte {
+		h := hmac.New(sha256.New, dataKey)
+		h.Write([]byte(label))
+		return h.Sum(nil)
+	}
+	block, err := aes.NewCipher(derive("secret:enc"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &valueKeys{aead: aead, ivKey: derive("secret:iv"), mac: derive("secret:mac")}, nil
+}
+
+// seal шифрует значение. IV выводится из ключа и значения, поэтому
+// неизмененное значение дает тот же шифротекст и не шумит в diff.
+// Имя ключа аутентифицируется, значения нельзя переставить между ключами.
+func (k *valueKeys) seal(key string, raw []byte) string {
+	h := hmac.New(sha256.New, k.ivKey)
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write(raw)
+	iv := h.Sum(nil)[:k.aead.NonceSize()]
+	data := k.aead.Seal(nil, iv, raw, []byte(key))
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s]",
+		base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(iv))
+}
+
+func (k *valueKeys) open(key, data, iv string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(nonce) != k.aead.NonceSize() {
+		return nil, fmt.Errorf("неверный IV")
+	}
+	return k.aead.Open(nil, nonce, ciphertext, []byte(key))
+}
+
+// writeMACValue добавляет зашифрованное значение к данным для MAC
+func writeMACValue(buf *bytes.Buffer, key string, raw []byte) {
+	buf.WriteString(key)
+	buf.WriteByte(0)
+	buf.Write(raw)
+	buf.WriteByte(0)
+}
+
+// sum возвращает MAC данных
+func (k *valueKeys) sum(document []byte) string {
+	h := hmac.New(sha256.New, k.mac)
+	h.Write(document)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,228 @@
+package backends
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// sealBackend — основной бэкенд для тестов values: ключ данных
+// «шифруется» префиксом, файлы целиком не поддерживаются
+type sealBackend struct {
+	Backend
+}
+
+func (sealBackend) Name() string { return "test" }
+
+func (sealBackend) Ext() string { return ".enc" }
+
+func (sealBackend) Seal(file string, plaintext []byte) ([]byte, error) {
+	return append([]byte("sealed:"), plaintext...), nil
+}
+
+func (sealBackend) Open(ciphertext []byte) ([]byte, error) {
+	return bytes.TrimPrefix(ciphertext, []byte("sealed:")), nil
+}
+
+func newTestValues(t *testing.T, markers *config.Markers) *valuesBackend {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	return &valuesBackend{Backend: sealBackend{}, cfg: &config.Config{GPGKey: "test", Markers: markers}}
+}
+
+// encryptValues шифрует content как file и возвращает содержимое file.enc
+func encryptValues(t *testing.T, v *valuesBackend, file, content string) string {
+	t.Helper()
+	if err := v.EncryptBytes(file, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := os.ReadFile(file + v.Ext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(sealed)
+}
+
+var valuesFormats = []struct {
+	file    string
+	content string
+	secrets []string
+	keys    []string
+	// inject добавляет открытое значение в зашифрованный файл
+	inject [2]string
+}{
+	{
+		file:    ".env",
+		content: "# База\nDB_PASSWORD=hunter22\nexport API_KEY=\"k-123 456\"\nMULTI=\"one\ntwo\"\n",
+		secrets: []string{"hunter22", "k-123 456", "one\ntwo"},
+		keys:    []string{"# База", "DB_PASSWORD=", "export API_KEY=", "MULTI="},
+		inject:  [2]string{"DB_PASSWORD=", "INJECTED=1\nDB_PASSWORD="},
+	},
+	{
+		file:    "config.yaml",
+		content: "db:\n  password: hunter22 # main\n  hosts:\n    - primary-host\n    - replica-host\ncert: |\n  BEGIN\n  END\n",
+		secrets: []string{"hunter22", "primary-host", "replica-host", "BEGIN"},
+		keys:    []string{"db:", "password:", "hosts:", "cert:", "# main"},
+		inject:  [2]string{"cert:", "injected: 1\ncert:"},
+	},
+	{
+		file:    "config.json",
+		content: "{\n  \"db\": {\"password\": \"hunter22\", \"port\": 5432},\n  \"tokens\": [\"t-one\", \"t-two\"]\n}\n",
+		secrets: []string{"hunter22", "5432", "t-one", "t-two"},
+		keys:    []string{`"db"`, `"password"`, `"port"`, `"tokens"`},
+		inject:  [2]string{`"tokens"`, "\"injected\": 1,\n  \"tokens\""},
+	},
+	{
+		file:    "config.toml",
+		content: "[db]\npassword = \"hunter22\"\nport = 5432\nkey = \"\"\"\nmulti\nline\"\"\"\n",
+		secrets: []string{"hunter22", "5432", "multi\nline"},
+		keys:    []string{"[db]", "password = ", "port = ", "key = "},
+		inject:  [2]string{"port = ", "injected = 1\nport = "},
+	},
+	{
+		file:    "app.ini",
+		content: "; comment\n[db]\npassword = hunter22\nuser=admin\n",
+		secrets: []string{"hunter22", "admin"},
+		keys:    []string{"; comment", "[db]", "password = ", "user="},
+		inject:  [2]string{"user=", "injected = 1\nuser="},
+	},
+}
+
+func TestValuesRoundTrip(t *testing.T) {
+	for _, tt := range valuesFormats {
+		t.Run(tt.file, func(t *testing.T) {
+			v := newTestValues(t, nil)
+			sealed := encryptValues(t, v, tt.file, tt.content)
+
+			if !LooksEncrypted([]byte(sealed)) {
+				t.Errorf("LooksEncrypted = false:\n%s", sealed)
+			}
+			for _, s := range tt.secrets {
+				if strings.Contains(sealed, s) {
+					t.Errorf("value %q left in plaintext:\n%s", s, sealed)
+				}
+			}
+			for _, k := range tt.keys {
+				if !strings.Contains(sealed, k) {
+					t.Errorf("key %q not readable:\n%s", k, sealed)
+				}
+			}
+
+			got, err := v.DecryptBytes(tt.file + v.Ext())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.content {
+				t.Errorf("decrypted = %q, want %q", got, tt.content)
+			}
+
+			// Неизмененный файл шифруется в тот же шифротекст
+			if again := encryptValues(t, v, tt.file, tt.content); again != sealed {
+				t.Errorf("re-encryption changed ciphertext:\n%s\n---\n%s", sealed, again)
+			}
+		})
+	}
+}
+
+func TestValuesOnlyChangedValueDiffers(t *testing.T) {
+	v := newTestValues(t, nil)
+	before := strings.Split(encryptValues(t, v, ".env", "A=1\nB=2\n"), "\n")
+	after := strings.Split(encryptValues(t, v, ".env", "A=1\nB=3\n"), "\n")
+	// Заголовок (MAC) и строка B меняются, строка A — нет
+	if before[3] != after[3] || before[4] == after[4] || before[2] == after[2] {
+		t.Errorf("unexpected diff:\n%v\n%v", before, after)
+	}
+}
+
+func TestValuesMACTamper(t *testing.T) {
+	for _, tt := range valuesFormats {
+		t.Run(tt.file, func(t *testing.T) {
+			v := newTestValues(t, nil)
+			sealed := encryptValues(t, v, tt.file, tt.content)
+
+			// Значение, добавленное в обход secret, хранится открытым
+			tampered := strings.Replace(sealed, tt.inject[0], tt.inject[1], 1)
+			if err := os.WriteFile(tt.file+v.Ext(), []byte(tampered), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := v.DecryptBytes(tt.file + v.Ext())
+			if err == nil || !strings.Contains(err.Error(), "MAC") {
+				t.Errorf("err = %v, want MAC mismatch", err)
+			}
+		})
+	}
+}
+
+func TestValuesRenamedKey(t *testing.T) {
+	v := newTestValues(t, nil)
+	sealed := encryptValues(t, v, ".env", "USER=admin\n")
+	if err := os.WriteFile(".env.enc", []byte(strings.Replace(sealed, "USER=", "ADMIN=", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.DecryptBytes(".env.enc"); err == nil {
+		t.Error("renamed key decrypted without error")
+	}
+}
+
+var encToken = regexp.MustCompile(`ENC\[AES256_GCM,data:[^\]]*\]`)
+
+func TestValuesSwapAcrossKeys(t *testing.T) {
+	v := newTestValues(t, nil)
+	sealed := encryptValues(t, v, ".env", "USER=admin\nPASSWORD=hunter22\n")
+	tokens := encToken.FindAllString(sealed, -1)
+	if len(tokens) != 2 {
+		t.Fatalf("tokens = %v", tokens)
+	}
+	swapped := strings.NewReplacer(tokens[0], tokens[1], tokens[1], tokens[0]).Replace(sealed)
+	if err := os.WriteFile(".env.enc", []byte(swapped), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := v.DecryptBytes(".env.enc")
+	if err == nil || !strings.Contains(err.Error(), "не удалось расшифровать значение") {
+		t.Errorf("err = %v, want authentication failure", err)
+	}
+}
+
+func TestValuesMarkers(t *testing.T) {
+	v := newTestValues(t, &config.Markers{})
+	content := "HOST=localhost\nDB_PASSWORD=hunter22 # secret\nTOKEN_secret=abc\n"
+	sealed := encryptValues(t, v, ".env", content)
+	if !strings.Contains(sealed, "HOST=localhost") {
+		t.Errorf("unmarked value encrypted:\n%s", sealed)
+	}
+	if strings.Contains(sealed, "hunter22") || strings.Contains(sealed, "=abc") {
+		t.Errorf("marked value left in plaintext:\n%s", sealed)
+	}
+
+	// Открытые значения можно править вручную: MAC их не покрывает
+	edited := strings.Replace(sealed, "HOST=localhost", "HOST=db.internal", 1)
+	if err := os.WriteFile(".env.enc", []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.DecryptBytes(".env.enc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(content, "localhost", "db.internal", 1); string(got) != want {
+		t.Errorf("decrypted = %q, want %q", got, want)
+	}
+
+	// Удаление зашифрованного значения MAC обнаруживает
+	lines := strings.Split(edited, "\n")
+	var kept []string
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "TOKEN_secret=") {
+			kept = append(kept, l)
+		}
+	}
+	if err := os.WriteFile(".env.enc", []byte(strings.Join(kept, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.DecryptBytes(".env.enc"); err == nil || !strings.Contains(err.Error(), "MAC") {
+		t.Errorf("err = %v, want MAC mismatch", err)
+	}
+}
@@ -0,0 +1,415 @@
+package backends
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Avdushin/secret/internal/envfile"
+	"github.com/Avdushin/secret/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register("vault", func(cfg *config.Config) (Backend, error) {
+		return NewVaultBackend(cfg, nil)
+	})
+}
+
+// VaultBackend хранит секреты в HashiCorp Vault (KV v2).
+// Вместо шифротекста рядом с файлом создается указатель .vault с путем
+// и версией секрета — его можно коммитить в репозиторий.
+type VaultBackend struct {
+	cfg    *config.Config
+	vcfg   config.VaultConfig
+	client *http.Client
+	token  string
+}
+
+// vaultPointer — содержимое файла-указателя .vault
+type vaultPointer struct {
+	Path    string   `yaml:"path"`
+	Version int      `yaml:"version"`
+	Format  string   `yaml:"format"`
+	Keys    []string `yaml:"keys,omitempty"`
+}
+
+const (
+	vaultFormatEnv    = "env"
+	vaultFormatFile   = "file"
+	vaultFormatBase64 = "base64"
+	vaultContentField = "content"
+)
+
+// NewVaultBackend создает бэкенд Vault. Если client равен nil,
+// используется HTTP-клиент с таймаутом по умолчанию.
+func NewVaultBackend(cfg *config.Config, client *http.Client) (*VaultBackend, error) {
+	var vcfg config.VaultConfig
+	if cfg.Vault != nil {
+		vcfg = *cfg.Vault
+	}
+	if vcfg.Address == "" {
+		vcfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if vcfg.Namespace == "" {
+		vcfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if vcfg.Mount == "" {
+		vcfg.Mount = "secret"
+	}
+	if vcfg.Path == "" {
+		vcfg.Path = cfg.ProjectName
+	}
+	if vcfg.Auth == "" {
+		vcfg.Auth = "token"
+	}
+	if vcfg.AppRolePath == "" {
+		vcfg.AppRolePath = "approle"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &VaultBackend{cfg: cfg, vcfg: vcfg, client: client}, nil
+}
+
+func (v *VaultBackend) Name() string { return "vault" }
+
+func (v *VaultBackend) Ext() string { return ".vault" }
+
+// Encrypt отправляет файл в Vault. Для .env файлов каждый ключ
+// сохраняется отдельным полем секрета, остальные файлы — целиком.
+func (v *VaultBackend) Encrypt(file string) error {
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return err
+	}
+	if err := v.EncryptBytes(file, content); err != nil {
+		return err
+	}
+	pointer, err := readVaultPointer(file + v.Ext())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s сохранен в Vault (%s/%s, версия %d)\n", file, v.vcfg.Mount, pointer.Path, pointer.Version)
+	return nil
+}
+
+// EncryptBytes отправляет содержимое файла file в Vault, не читая его с диска
+func (v *VaultBackend) EncryptBytes(file string, content []byte) error {
+	pointer := vaultPointer{Path: v.secretPath(file)}
+	data := map[string]string{}
+	switch {
+	case envfile.IsEnvFile(file):
+		pointer.Format = vaultFormatEnv
+		for _, e := range envfile.Parse(string(content)) {
+			if _, dup := data[e.Key]; !dup {
+				pointer.Keys = append(pointer.Keys, e.Key)
+			}
+			data[e.Key] = e.Value
+		}
+	case utf8.Valid(content):
+		pointer.Format = vaultFormatFile
+		data[vaultContentField] = string(content)
+	default:
+		pointer.Format = vaultFormatBase64
+		data[vaultContentField] = base64.StdEncoding.EncodeToString(content)
+	}
+
+	var resp struct {
+		Data struct {
+			Version int `json:"version"`
+		} `json:"data"`
+	}
+	body := map[string]any{"data": data}
+	if err := v.request(http.MethodPost, v.dataURL(pointer.Path), body, &resp); err != nil {
+		return fmt.Errorf("ошибка записи в Vault: %v", err)
+	}
+	pointer.Version = resp.Data.Version
+
+	outFile := file + v.Ext()
+	if err := writeVaultPointer(outFile, pointer); err != nil {
+		return err
+	}
+	// Создаем .example файл
+	if err := writeExampleFile(file, content, v.cfg.Markers); err != nil {
+		return fmt.Errorf("не удалось создать .example файл: %v", err)
+	}
+	return nil
+}
+
+// Decrypt читает указатель .vault и восстанавливает файл из Vault
+func (v *VaultBackend) Decrypt(file string) error {
+	content, err := v.DecryptBytes(file)
+	if err != nil {
+		return err
+	}
+	outFile := strings.TrimSuffix(file, v.Ext())
+	if err := os.WriteFile(outFile, content, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Файл %s восстановлен из Vault в %s\n", file, outFile)
+	return nil
+}
+
+func (v *VaultBackend) DecryptBytes(file string) ([]byte, error) {
+	pointer, err := readVaultPointer(file)
+	if err != nil {
+		return nil, err
+	}
+
+	u := v.dataURL(pointer.Path)
+	if pointer.Version > 0 {
+		u += "?version=" + fmt.Sprint(pointer.Version)
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.request(http.MethodGet, u, nil, &resp); err != nil {
+		return nil, fmt.Errorf("ошибка чтения из Vault: %v", err)
+	}
+	return pointer.restore(resp.Data.Data)
+}
+
+// restore собирает содержимое файла из полей секрета
+func (p vaultPointer) restore(fields map[string]string) ([]byte, error) {
+	switch p.Format {
+	case vaultFormatEnv:
+		keys := p.Keys
+		if len(keys) == 0 {
+			for k := range fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+		}
+		entries := make([]envfile.Entry, 0, len(keys))
+		for _, k := range keys {
+			entries = append(entries, envfile.Entry{Key: k, Value: fields[k]})
+		}
+		return []byte(envfile.Format(entries)), nil
+	case vaultFormatBase64:
+		return base64.StdEncoding.DecodeString(fields[vaultContentField])
+	default:
+		return []byte(fields[vaultContentField]), nil
+	}
+}
+
+func readVaultPointer(file string) (*vaultPointer, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("файл %s не существует", file)
+	} else if err != nil {
+		return nil, err
+	}
+	var pointer vaultPointer
+	if err := yaml.Unmarshal(data, &pointer); err != nil || pointer.Path == "" {
+		return nil, fmt.Errorf("файл %s не является указателем Vault", file)
+	}
+	return &pointer, nil
+}
+
+func writeVaultPointer(file string, pointer vaultPointer) error {
+	data, err := yaml.Marshal(pointer)
+	if err != nil {
+		return err
+	}
+	header := "# Секрет хранится в HashiCorp Vault. Восстановить: secret decrypt " + filepath.Base(file) + "\n"
+	return os.WriteFile(file, append([]byte(header), data...), 0644)
+}
+
+// secretPath возвращает путь секрета внутри KV: <path>/<файл>
+func (v *VaultBackend) secretPath(file string) string {
+	rel := filepath.ToSlash(filepath.Clean(file))
+	return strings.Trim(path.Join(v.vcfg.Path, rel), "/")
+}
+
+func (v *VaultBackend) dataURL(secretPath string) string {
+	return "/v1/" + v.vcfg.Mount + "/data/" + escapeVaultPath(secretPath)
+}
+
+func escapeVaultPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// request выполняет запрос к API Vault и декодирует JSON-ответ в out
+func (v *VaultBackend) request(method, apiPath string, body, out any) error {
+	if v.token == "" {
+		if err := v.login(); err != nil {
+			return err
+		}
+	}
+	return v.do(method, apiPath, v.token, body, out)
+}
+
+func (v *VaultBackend) do(method, apiPath, token string, body, out any) error {
+	if v.vcfg.Address == "" {
+		return fmt.Errorf("не задан адрес Vault (vault.address в конфиге или VAULT_ADDR)")
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(v.vcfg.Address, "/")+apiPath, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.vcfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.vcfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && len(apiErr.Errors) > 0 {
+			return fmt.Errorf("%s %s: %s", resp.Status, apiPath, strings.Join(apiErr.Errors, "; "))
+		}
+		return fmt.Errorf("%s %s", resp.Status, apiPath)
+	}
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// login получает токен: из окружения/~/.vault-token или через AppRole
+func (v *VaultBackend) login() error {
+	switch v.vcfg.Auth {
+	case "token":
+		if token := os.Getenv("VAULT_TOKEN"); token != "" {
+			v.token = token
+			return nil
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				v.token = strings.TrimSpace(string(data))
+				return nil
+			}
+		}
+		return fmt.Errorf("не найден токен Vault: задайте VAULT_TOKEN или выполните vault login")
+	case "approle":
+		roleID := v.vcfg.RoleID
+		if roleID == "" {
+			roleID = os.Getenv("VAULT_ROLE_ID")
+		}
+		secretID := os.Getenv("VAULT_SECRET_ID")
+		if roleID == "" || secretID == "" {
+			return fmt.Errorf("для AppRole нужны vault.role_id (или VAULT_ROLE_ID) и VAULT_SECRET_ID")
+		}
+		var resp struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		body := map[string]string{"role_id": roleID, "secret_id": secretID}
+		if err := v.do(http.MethodPost, "/v1/auth/"+v.vcfg.AppRolePath+"/login", "", body, &resp); err != nil {
+			return fmt.Errorf("ошибка входа через AppRole: %v", err)
+		}
+		if resp.Auth.ClientToken == "" {
+			return fmt.Errorf("ошибка входа через AppRole: Vault не вернул токен")
+		}
+		v.token = resp.Auth.ClientToken
+		return nil
+	default:
+		return fmt.Errorf("неизвестный способ аутентификации Vault: %s (доступны: token, approle)", v.vcfg.Auth)
+	}
+}
+
+// Ключами шифрования управляет Vault
+func (v *VaultBackend) GenerateKey(p KeyParams) (string, error) { return "", ErrNotSupported }
+
+func (v *VaultBackend) ExportKey(keyID string, secret bool) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (v *VaultBackend) ImportKey(path string) error { return ErrNotSupported }
+
+// Доступ к файлам определяется политиками сервиса
+func (v *VaultBackend) CanDecrypt(file string) error { return ErrNotSupported }
+
+func (v *VaultBackend) Seal(file string, plaintext []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (v *VaultBackend) Open(ciphertext []byte) ([]byte, error) { return nil, ErrNotSupported }
+
+func (v *VaultBackend) AddRecipient(ref string) ([]string, error) { return nil, ErrNotSupported }
+
+func (v *VaultBackend) Sign(keyID string, data []byte) ([]byte, error) { return nil, ErrNotSupported }
+
+func (v *VaultBackend) Verify(data, signature, publicKey []byte) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (v *VaultBackend) FindKey(query string) (string, error) { return "", ErrNotSupported }
+
+func (v *VaultBackend) KeyInfo(keyID string) (*KeyInfo, error) { return nil, ErrNotSupported }
+
+func (v *VaultBackend) DeleteKey(keyID string) error { return ErrNotSupported }
+
+func (v *VaultBackend) ListKeys() (string, error) {
+	info, err := v.lookupSelf()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Vault: %s\nKV: %s/%s\nТокен: %s (политики: %s)\n",
+		v.vcfg.Address, v.vcfg.Mount, v.vcfg.Path, info.DisplayName, strings.Join(info.Policies, ", ")), nil
+}
+
+func (v *VaultBackend) Check() error {
+	_, err := v.lookupSelf()
+	return err
+}
+
+type vaultTokenInfo struct {
+	DisplayName string   `json:"display_name"`
+	Policies    []string `json:"policies"`
+}
+
+func (v *VaultBackend) lookupSelf() (*vaultTokenInfo, error) {
+	var resp struct {
+		Data vaultTokenInfo `json:"data"`
+	}
+	if err := v.request(http.MethodGet, "/v1/auth/token/lookup-self", nil, &resp); err != nil {
+		return nil, fmt.Errorf("ошибка проверки токена Vault: %v", err)
+	}
+	return &resp.Data, nil
+}
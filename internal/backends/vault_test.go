@@ -0,0 +1,267 @@
+package backends
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Avdushin/secret/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeVault — минимальный KV v2 с входом по токену и AppRole
+type fakeVault struct {
+	mu       sync.Mutex
+	token    string
+	roleID   string
+	secretID string
+	logins   int
+	secrets  map[string][]map[string]string
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	t.Helper()
+	fv := &fakeVault{token: "s.test", roleID: "role", secretID: "secret", secrets: map[string][]map[string]string{}}
+	srv := httptest.NewServer(fv)
+	t.Cleanup(srv.Close)
+	return fv, srv
+}
+
+func (fv *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fv.mu.Lock()
+	defer fv.mu.Unlock()
+
+	reply := func(status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	fail := func(status int, msg string) {
+		reply(status, map[string][]string{"errors": {msg}})
+	}
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		var body struct {
+			RoleID   string `json:"role_id"`
+			SecretID string `json:"secret_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RoleID != fv.roleID || body.SecretID != fv.secretID {
+			fail(http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		fv.logins++
+		reply(http.StatusOK, map[string]any{"auth": map[string]string{"client_token": fv.token}})
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != fv.token {
+		fail(http.StatusForbidden, "permission denied")
+		return
+	}
+
+	secretPath, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/data/")
+	if !ok {
+		fail(http.StatusNotFound, "unsupported path")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		fv.secrets[secretPath] = append(fv.secrets[secretPath], body.Data)
+		reply(http.StatusOK, map[string]any{"data": map[string]int{"version": len(fv.secrets[secretPath])}})
+	case http.MethodGet:
+		versions := fv.secrets[secretPath]
+		version := len(versions)
+		if v := r.URL.Query().Get("version"); v != "" {
+			version, _ = strconv.Atoi(v)
+		}
+		if version < 1 || version > len(versions) {
+			fail(http.StatusNotFound, "")
+			return
+		}
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"data": versions[version-1]}})
+	default:
+		fail(http.StatusMethodNotAllowed, "")
+	}
+}
+
+func newTestVault(t *testing.T, srv *httptest.Server, vcfg config.VaultConfig) *VaultBackend {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	vcfg.Address = srv.URL
+	v, err := NewVaultBackend(&config.Config{ProjectName: "proj", Vault: &vcfg}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func readPointer(t *testing.T, file string) vaultPointer {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p vaultPointer
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestVaultTokenEnvRoundTrip(t *testing.T) {
+	fv, srv := newFakeVault(t)
+	t.Setenv("VAULT_TOKEN", fv.token)
+	v := newTestVault(t, srv, config.VaultConfig{})
+
+	content := "# comment\nDB_PASSWORD=\"p@ss word\"\nAPI_KEY=abc\n"
+	if err := v.EncryptBytes(".env", []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := readPointer(t, ".env.vault")
+	if p.Path != "proj/.env" || p.Version != 1 || p.Format != vaultFormatEnv {
+		t.Fatalf("pointer = %+v", p)
+	}
+	if strings.Join(p.Keys, ",") != "DB_PASSWORD,API_KEY" {
+		t.Errorf("pointer keys = %v", p.Keys)
+	}
+	stored := fv.secrets["proj/.env"][0]
+	if stored["DB_PASSWORD"] != "p@ss word" || stored["API_KEY"] != "abc" {
+		t.Errorf("stored = %v", stored)
+	}
+	if _, err := os.Stat(ExampleName(".env")); err != nil {
+		t.Errorf(".example not written: %v", err)
+	}
+
+	got, err := v.DecryptBytes(".env.vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "DB_PASSWORD=\"p@ss word\"\nAPI_KEY=abc\n"; string(got) != want {
+		t.Errorf("decrypted = %q, want %q", got, want)
+	}
+}
+
+func TestVaultPointerPinsVersion(t *testing.T) {
+	fv, srv := newFakeVault(t)
+	t.Setenv("VAULT_TOKEN", fv.token)
+	v := newTestVault(t, srv, config.VaultConfig{})
+
+	if err := v.EncryptBytes("config.json", []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.ReadFile("config.json.vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.EncryptBytes("config.json", []byte(`{"a":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if p := readPointer(t, "config.json.vault"); p.Version != 2 || p.Format != vaultFormatFile {
+		t.Fatalf("pointer = %+v", p)
+	}
+
+	// Старый указатель читает свою версию, а не последнюю
+	if err := os.WriteFile("old.json.vault", old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.DecryptBytes("old.json.vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"a":1}` {
+		t.Errorf("old version = %q", got)
+	}
+
+	if err := v.Decrypt("config.json.vault"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile("config.json"); string(got) != `{"a":2}` {
+		t.Errorf("restored = %q", got)
+	}
+}
+
+func TestVaultBinaryRoundTrip(t *testing.T) {
+	fv, srv := newFakeVault(t)
+	t.Setenv("VAULT_TOKEN", fv.token)
+	v := newTestVault(t, srv, config.VaultConfig{})
+
+	content := []byte{0xff, 0x00, 0xfe, 'k', 'e', 'y'}
+	if err := v.EncryptBytes("id.key", content); err != nil {
+		t.Fatal(err)
+	}
+	if p := readPointer(t, "id.key.vault"); p.Format != vaultFormatBase64 {
+		t.Fatalf("format = %s", p.Format)
+	}
+	got, err := v.DecryptBytes("id.key.vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("decrypted = %q", got)
+	}
+}
+
+func TestVaultTokenFile(t *testing.T) {
+	fv, srv := newFakeVault(t)
+	home := t.TempDir()
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("HOME", home)
+	v := newTestVault(t, srv, config.VaultConfig{})
+
+	if err := v.EncryptBytes(".env", []byte("A=1\n")); err == nil || !strings.Contains(err.Error(), "VAULT_TOKEN") {
+		t.Fatalf("err = %v, want missing token", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".vault-token"), []byte(fv.token+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.EncryptBytes(".env", []byte("A=1\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultAppRoleLogin(t *testing.T) {
+	fv, srv := newFakeVault(t)
+	t.Setenv("VAULT_TOKEN", "s.wrong")
+	t.Setenv("VAULT_SECRET_ID", fv.secretID)
+	v := newTestVault(t, srv, config.VaultConfig{Auth: "approle", RoleID: fv.roleID})
+
+	if err := v.EncryptBytes("a.txt", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.DecryptBytes("a.txt.vault"); err != nil {
+		t.Fatal(err)
+	}
+	if fv.logins != 1 {
+		t.Errorf("logins = %d, want 1 (token reused)", fv.logins)
+	}
+
+	t.Setenv("VAULT_SECRET_ID", "bad")
+	v = newTestVault(t, srv, config.VaultConfig{Auth: "approle", RoleID: fv.roleID})
+	if err := v.EncryptBytes("a.txt", []byte("one")); err == nil || !strings.Contains(err.Error(), "invalid role") {
+		t.Fatalf("err = %v, want AppRole failure", err)
+	}
+}
+
+func TestVaultRejectsNonPointer(t *testing.T) {
+	_, srv := newFakeVault(t)
+	v := newTestVault(t, srv, config.VaultConfig{})
+	if err := os.WriteFile("x.vault", []byte("not: [a pointer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.DecryptBytes("x.vault"); err == nil {
+		t.Fatal("expected error for non-pointer file")
+	}
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// loadBackend загружает конфиг проекта и создает указанный в нем бэкенд.
+// Неизвестный бэкенд приводит к ошибке сразу при загрузке конфига.
+func loadBackend() (*config.Config, backends.Backend, error) {
+	return loadEnvBackend("")
+}
+
+// loadEnvBackend загружает конфиг с настройками окружения env
+// (пустая строка — основной конфиг) и создает бэкенд для него
+func loadEnvBackend(env string) (*config.Config, backends.Backend, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки конфига: %v", err)
+	}
+	if cfg, err = cfg.ForEnv(env); err != nil {
+		return nil, nil, err
+	}
+	b, err := backends.New(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки конфига: %v", err)
+	}
+	return cfg, b, nil
+}
+
+// keyFilePrefix возвращает префикс имен файлов экспортированных ключей
+func keyFilePrefix(cfg *config.Config) string {
+	if cfg.ProjectName == "" {
+		return "key"
+	}
+	return strings.ToLower(strings.ReplaceAll(cfg.ProjectName, " ", "_"))
+}
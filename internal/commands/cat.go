@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/spf13/cobra"
+)
+
+// @ cat cmd
+func CatCmd() *cobra.Command {
+	var env string
+
+	cmd := &cobra.Command{
+		Use:   "cat <file.gpg>...",
+		Short: "Выводит расшифрованный файл в stdout",
+		Long: `Расшифровывает файлы в память и выводит их в stdout, не создавая
+открытых файлов в рабочей директории. Удобно для передачи секретов в
+другие программы:
+  secret cat k8s-secret.yaml.gpg | kubectl apply -f -
+  secret cat config.json.gpg | jq .database`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, backend, err := loadEnvBackend(env)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			for _, file := range args {
+				if err := writePlaintext(backend, file); err != nil {
+					fmt.Fprintf(os.Stderr, "❌ Ошибка: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Окружение из environments")
+	return cmd
+}
+
+// writePlaintext расшифровывает файл и пишет его в stdout.
+// Можно указать и имя открытого файла: расширение бэкенда добавится само.
+func writePlaintext(backend backends.Backend, file string) error {
+	if !strings.HasSuffix(file, backend.Ext()) {
+		file += backend.Ext()
+	}
+	content, err := backend.DecryptBytes(file)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
@@ -2,12 +2,13 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/Avdushin/secret/internal/backends"
 	"github.com/Avdushin/secret/pkg/config"
 	"github.com/spf13/cobra"
 )
@@ -15,51 +16,85 @@ import (
 // @ check cmd
 func CheckCmd() *cobra.Command {
 	var showAll bool
+	var env string
 
 	cmd := &cobra.Command{
 		Use:   "check",
-		Short: "Проверяет доступность GPG ключей",
-		Long: `Проверяет доступность GPG ключей.
+		Short: "Проверяет доступность ключей проекта",
+		Long: `Проверяет доступность ключей проекта.
 По умолчанию показывает ключ текущего проекта.
 С флагом --all показывает все доступные ключи.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			// Без конфига проверяем ключи бэкенда по умолчанию
+			cfg, err := config.LoadConfig()
+			hasConfig := err == nil
+			if !hasConfig {
+				cfg = &config.Config{Backend: config.DefaultBackend}
+			}
+			if cfg, err = cfg.ForEnv(env); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+
+			backend, err := backends.New(cfg)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+
 			if showAll {
 				// Показываем все ключи
-				checkAllKeys()
+				checkAllKeys(backend)
 			} else {
 				// Показываем ключ проекта
-				checkProjectKey()
+				checkProjectKey(cfg, backend, hasConfig)
 			}
 		},
 	}
 
-	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "Показать все доступные GPG ключи")
+	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "Показать все доступные ключи")
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Проверить ключ окружения из environments")
 	return cmd
 }
 
-// ? все доступные GPG ключи
-func checkAllKeys() {
-	fmt.Println("🔍 Проверяем все доступные GPG ключи...")
-	out, err := exec.Command("gpg", "--list-secret-keys", "--keyid-format=LONG").CombinedOutput()
+// ? все доступные ключи бэкенда
+func checkAllKeys(backend backends.Backend) {
+	fmt.Printf("🔍 Проверяем все доступные ключи (%s)...\n", backend.Name())
+	out, err := backend.ListKeys()
 	if err != nil {
 		fmt.Printf("❌ Ошибка при получении списка ключей: %v\n", err)
 		return
 	}
-	fmt.Println(string(out))
+	fmt.Println(out)
 }
 
 // ? Ключ текущего проекта
-func checkProjectKey() {
-	// Сначала пробуем загрузить конфиг проекта
-	cfg, err := config.LoadConfig()
-	var projectKey string
-	if err == nil && cfg.GPGKey != "" {
+func checkProjectKey(cfg *config.Config, backend backends.Backend, hasConfig bool) {
+	// Бэкенды без локальных ключей (например, Vault) проверяют только доступ
+	if _, err := backend.FindKey(""); errors.Is(err, backends.ErrNotSupported) {
+		fmt.Printf("🔐 Проверяем доступ к бэкенду %s... ", backend.Name())
+		if err := backend.Check(); err != nil {
+			fmt.Println("❌")
+			fmt.Printf("Ошибка: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ OK")
+		checkTrackedPlaintext(cfg)
+		return
+	}
+
+	// Без gpg_key ключом проекта считается первый получатель
+	recipientKeys := len(cfg.Recipients) > 0 && backend.Name() != "age"
+	if hasConfig && cfg.GPGKey == "" && recipientKeys {
+		cfg.GPGKey = cfg.Recipients[0]
+	}
+
+	if hasConfig && cfg.GPGKey != "" {
 		// Используем ключ из конфига
-		projectKey = cfg.GPGKey
-		fmt.Printf("🔍 Проверяем ключ проекта из конфига: %s\n", projectKey)
+		fmt.Printf("🔍 Проверяем ключ проекта из конфига: %s\n", cfg.GPGKey)
 	} else {
 		// Пытаемся автоматически определить ключ проекта по имени директории
-		projectKey, err = detectProjectKeyFromDir()
+		projectKey, err := detectProjectKeyFromDir(backend)
 		if err != nil {
 			fmt.Printf("❌ Не удалось определить ключ проекта: %v\n", err)
 			fmt.Println("Возможные решения:")
@@ -68,81 +103,83 @@ func checkProjectKey() {
 			fmt.Println("3. Укажите ключ вручную: secret check --all")
 			os.Exit(1)
 		}
+		cfg.GPGKey = projectKey
 		fmt.Printf("🔍 Автоматически определили ключ проекта: %s\n", projectKey)
 	}
 
 	// Проверяем существует ли ключ
-	checkCmd := exec.Command("gpg", "--list-keys", projectKey)
-	if output, err := checkCmd.CombinedOutput(); err != nil {
-		fmt.Printf("❌ Ключ проекта не найден в GPG: %s\n", projectKey)
-		fmt.Printf("Вывод: %s\n", string(output))
+	info, err := backend.KeyInfo(cfg.GPGKey)
+	if err != nil {
+		fmt.Printf("❌ Ключ проекта не найден: %s\n", cfg.GPGKey)
+		fmt.Printf("Вывод: %v\n", err)
 		fmt.Printf("Возможно ключ был удален или не импортирован\n")
 		fmt.Println("Попробуйте импортировать ключ: secret import")
 		os.Exit(1)
-	} else {
-		// Показываем информацию о ключе проекта
-		fmt.Printf("✅ Ключ проекта найден:\n")
-		// Получаем детальную информацию о ключе
-		detailCmd := exec.Command("gpg", "--list-secret-keys", "--keyid-format=LONG", projectKey)
-		if detailOutput, err := detailCmd.CombinedOutput(); err == nil {
-			lines := strings.Split(string(detailOutput), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, projectKey) || strings.Contains(line, "sec") || strings.Contains(line, "uid") {
-					fmt.Println(line)
-				}
+	}
+
+	// Показываем информацию о ключе проекта
+	fmt.Printf("✅ Ключ проекта найден:\n")
+	fmt.Printf("ID:          %s\n", info.ID)
+	fmt.Printf("Fingerprint: %s\n", info.Fingerprint)
+	fmt.Printf("uid:         %s\n", info.Name)
+
+	// Проверяем публичные ключи всех участников (у age получатели — сами ключи)
+	if recipientKeys {
+		fmt.Printf("\n👥 Получатели (%d):\n", len(cfg.Recipients))
+		for _, r := range cfg.Recipients {
+			if info, err := backend.KeyInfo(r); err != nil {
+				fmt.Printf("  ❌ %s — ключ не найден\n", r)
+			} else {
+				fmt.Printf("  ✅ %s %s\n", r, info.Name)
 			}
 		}
+	}
 
-		// Проверяем возможность шифрования/расшифровки
-		fmt.Printf("\n🔐 Проверяем возможность шифрования... ")
-		testEncryptCmd := exec.Command("gpg", "--encrypt", "--recipient", projectKey, "--armor", "--output", "/dev/null", "/dev/null")
-		if err := testEncryptCmd.Run(); err != nil {
-			fmt.Println("❌ Ошибка шифрования")
-			fmt.Printf("Возможно ключ поврежден или не имеет необходимых прав\n")
+	// Проверяем возможность шифрования/расшифровки
+	fmt.Printf("\n🔐 Проверяем возможность шифрования... ")
+	if err := backend.Check(); err != nil {
+		fmt.Println("❌ Ошибка шифрования")
+		fmt.Printf("Возможно ключ поврежден или не имеет необходимых прав: %v\n", err)
+	} else {
+		fmt.Println("✅ OK")
+	}
+
+	checkFileAccess(cfg, backend)
+	checkTrackedPlaintext(cfg)
+}
+
+// ? Какие зашифрованные файлы может прочитать текущий пользователь
+func checkFileAccess(cfg *config.Config, backend backends.Backend) {
+	files := getEncryptedFiles(cfg.SecretFiles.Patterns(), backend.Ext())
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Printf("\n📂 Доступ к файлам:\n")
+	denied := 0
+	for _, file := range files {
+		var groups string
+		if entry := cfg.SecretFiles.Find(file); entry != nil && len(entry.Groups) > 0 {
+			groups = " [" + strings.Join(entry.Groups, ", ") + "]"
+		}
+		if err := backend.CanDecrypt(file); err != nil {
+			fmt.Printf("  ❌ %s%s — %v\n", file, groups, err)
+			denied++
 		} else {
-			fmt.Println("✅ OK")
+			fmt.Printf("  ✅ %s%s\n", file, groups)
 		}
 	}
+	if denied > 0 {
+		fmt.Printf("Недоступно файлов: %d из %d\n", denied, len(files))
+	}
 }
 
 // ? Пытаетмся определить ключ проекта по имени текущей директории
-func detectProjectKeyFromDir() (string, error) {
+func detectProjectKeyFromDir(backend backends.Backend) (string, error) {
 	// Получаем имя текущей директории
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return "", err
 	}
-	dirName := filepath.Base(currentDir)
-
-	// Получаем список всех ключей
-	out, err := exec.Command("gpg", "--list-secret-keys", "--keyid-format=LONG").CombinedOutput()
-	if err != nil {
-		return "", err
-	}
-
-	lines := strings.Split(string(out), "\n")
-	for idx, line := range lines {
-		if strings.Contains(line, "uid") && strings.Contains(line, dirName) {
-			// Ищем "sec" в предыдущих строках (назад до 5 строк)
-			for j := 1; j <= 5; j++ {
-				if idx-j < 0 {
-					break
-				}
-				prevLine := lines[idx-j]
-				if strings.Contains(prevLine, "sec") {
-					parts := strings.Fields(prevLine)
-					if len(parts) >= 2 {
-						keyPart := parts[1]
-						if strings.Contains(keyPart, "/") {
-							keyParts := strings.Split(keyPart, "/")
-							if len(keyParts) == 2 {
-								return keyParts[1], nil
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-	return "", fmt.Errorf("не удалось найти ключ для проекта %s", dirName)
+	return backend.FindKey(filepath.Base(currentDir))
 }
@@ -5,39 +5,51 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/Avdushin/secret/internal/backends"
-	"github.com/Avdushin/secret/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 // @ decrypt cmd
 func DecryptCmd() *cobra.Command {
 	var allFiles bool
+	var env string
+	var stdout bool
 
 	cmd := &cobra.Command{
 		Use:   "decrypt [file]",
 		Short: "Расшифровывает файлы",
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			cfg, err := config.LoadConfig()
+			cfg, backend, err := loadEnvBackend(env)
 			if err != nil {
-				fmt.Printf("Ошибка загрузки конфига: %v\n", err)
+				fmt.Println(err)
 				os.Exit(1)
 			}
 
-			gpg := backends.NewGPGBackend(cfg)
+			// В режиме --stdout сообщения уходят в stderr, чтобы не смешиваться с данными
+			if stdout {
+				if len(args) != 1 {
+					fmt.Fprintln(os.Stderr, "❌ С флагом --stdout укажите один файл")
+					os.Exit(1)
+				}
+				if err := writePlaintext(backend, args[0]); err != nil {
+					fmt.Fprintf(os.Stderr, "❌ Ошибка: %v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
 
 			// Если указан конкретный файл
 			if len(args) == 1 {
-				if err := gpg.Decrypt(args[0]); err != nil {
+				if err := backend.Decrypt(args[0]); err != nil {
 					fmt.Printf("❌ Ошибка: %v\n", err)
 					os.Exit(1)
 				}
+				updateGitignore(backend.Ext(), plainName(backend, args[0]))
 				return
 			}
 
 			// Расшифровываем все зашифрованные файлы из конфига
-			filesToDecrypt := getEncryptedFiles(cfg.SecretFiles)
+			filesToDecrypt := getEncryptedFiles(cfg.SecretFiles.Patterns(), backend.Ext())
 			if len(filesToDecrypt) == 0 {
 				fmt.Println("ℹ️ Не найдено файлов для расшифровки")
 				return
@@ -45,23 +57,26 @@ func DecryptCmd() *cobra.Command {
 
 			fmt.Printf("🔓 Расшифровываем %d файлов...\n", len(filesToDecrypt))
 			for _, file := range filesToDecrypt {
-				if err := gpg.Decrypt(file); err != nil {
+				if err := backend.Decrypt(file); err != nil {
 					fmt.Printf("⚠️ Ошибка при расшифровке %s: %v\n", file, err)
 				}
 			}
+			updateGitignore(backend.Ext())
 
 			fmt.Println("✅ Все файлы обработаны")
 		},
 	}
 
 	cmd.Flags().BoolVarP(&allFiles, "all", "a", false, "Расшифровать все файлы из конфига")
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "Вывести расшифрованный файл в stdout, не создавая файл")
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Окружение из environments (только его ключ и файлы)")
 	return cmd
 }
 
-func getEncryptedFiles(patterns []string) []string {
+func getEncryptedFiles(patterns []string, ext string) []string {
 	var result []string
 	for _, pattern := range patterns {
-		matches, _ := filepath.Glob(pattern + ".gpg")
+		matches, _ := filepath.Glob(pattern + ext)
 		result = append(result, matches...)
 	}
 	return result
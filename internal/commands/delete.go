@@ -4,10 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/Avdushin/secret/internal/backends"
 	"github.com/Avdushin/secret/pkg/config"
 	"github.com/spf13/cobra"
 )
@@ -19,35 +19,46 @@ func DeleteKeyCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "delete-key",
-		Short: "Удаляет GPG-ключ проекта",
+		Short: "Удаляет ключ проекта",
 		Run: func(cmd *cobra.Command, args []string) {
 			cfg, err := config.LoadConfig()
+			hasConfig := err == nil
+			if !hasConfig {
+				cfg = &config.Config{Backend: config.DefaultBackend}
+			}
+
+			backend, err := backends.New(cfg)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
 
 			// Если конфиг не загружается или ключ в конфиге пустой,
-			// пытаемся найти ключ в GPG
+			// пытаемся найти ключ в бэкенде
 			var keyID string
-			if err != nil || cfg.GPGKey == "" {
-				fmt.Println("ℹ️  В конфиге проекта не найден GPG-ключ")
-				fmt.Println("🔍 Пытаемся найти ключ в GPG...")
+			if !hasConfig || cfg.GPGKey == "" {
+				fmt.Println("ℹ️  В конфиге проекта не найден ключ")
+				fmt.Printf("🔍 Пытаемся найти ключ в %s...\n", backend.Name())
 
 				// Пытаемся автоматически определить ключ проекта
-				autoKey, autoErr := autoDetectKey()
+				autoKey, autoErr := backend.FindKey("")
 				if autoErr != nil {
-					fmt.Println("❌ Не удалось найти GPG-ключ проекта")
+					fmt.Println("❌ Не удалось найти ключ проекта")
 					fmt.Println("Сначала выполните: secret init")
 					os.Exit(1)
 				}
 
 				keyID = autoKey
-				fmt.Printf("✅ Найден ключ в GPG: %s\n", keyID)
+				fmt.Printf("✅ Найден ключ в %s: %s\n", backend.Name(), keyID)
 			} else {
 				keyID = cfg.GPGKey
 			}
 
-			// Проверяем, существует ли ключ в GPG
-			if !keyExistsInGPG(keyID) {
-				fmt.Printf("❌ Ключ %s не найден в GPG\n", keyID)
-				if cfg.GPGKey != "" {
+			// Проверяем, существует ли ключ, и получаем информацию о нем
+			keyInfo, err := backend.KeyInfo(keyID)
+			if err != nil {
+				fmt.Printf("❌ Ключ %s не найден: %v\n", keyID, err)
+				if hasConfig && cfg.GPGKey != "" {
 					fmt.Println("Очищаем конфигурацию...")
 					cfg.GPGKey = ""
 					config.SaveConfig(cfg) // Игнорируем ошибку
@@ -55,29 +66,15 @@ func DeleteKeyCmd() *cobra.Command {
 				fmt.Println("Выполните: secret init")
 				os.Exit(1)
 			}
-
-			// Получаем информацию о ключе
-			keyInfo, err := getKeyInfo(keyID)
-			if err != nil {
-				fmt.Printf("❌ Ошибка получения информации о ключе: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Получаем полный fingerprint
-			fingerprint, err := getFingerprint(keyID)
-			if err != nil {
-				fmt.Printf("❌ Ошибка получения fingerprint ключа: %v\n", err)
-				os.Exit(1)
-			}
-			fmt.Printf("🔑 Fingerprint ключа: %s\n", fingerprint)
+			fmt.Printf("🔑 Fingerprint ключа: %s\n", keyInfo.Fingerprint)
 
 			reader := bufio.NewReader(os.Stdin)
 
 			if !force {
 				fmt.Printf("\nВы собираетесь удалить ключ проекта:\n")
 				fmt.Printf("ID: %s\n", keyID)
-				fmt.Printf("Имя: %s\n", keyInfo.name)
-				fmt.Printf("Email: %s\n", keyInfo.email)
+				fmt.Printf("Имя: %s\n", keyInfo.Name)
+				fmt.Printf("Email: %s\n", keyInfo.Email)
 				fmt.Print("\nПродолжить? (y/N): ")
 
 				confirm, _ := reader.ReadString('\n')
@@ -100,31 +97,33 @@ func DeleteKeyCmd() *cobra.Command {
 
 				if doBackup {
 					fmt.Println("\nСоздаем резервные копии ключей...")
-					if err := createBackup(cfg, keyID); err != nil {
+					if err := createBackup(cfg, backend, keyID); err != nil {
 						fmt.Printf("⚠️ Не удалось создать резервную копию: %v\n", err)
 						fmt.Println("Продолжаем без резервной копии")
 					}
 				}
 			}
 
-			// Удаляем ключ из GPG
-			fmt.Println("\nУдаляем ключ из GPG...")
-			if err := deleteKey(fingerprint); err != nil {
+			// Удаляем ключ
+			fmt.Printf("\nУдаляем ключ из %s...\n", backend.Name())
+			if err := backend.DeleteKey(keyInfo.Fingerprint); err != nil {
 				fmt.Printf("\n❌ Ошибка удаления ключа: %v\n", err)
-				printManualDeleteInstructions(fingerprint)
+				if backend.Name() == "gpg" {
+					printManualDeleteInstructions(keyInfo.Fingerprint)
+				}
 				os.Exit(1)
 			}
 
 			// Удаляем ключ из конфига (если он там был)
-			if cfg.GPGKey != "" {
+			if hasConfig && cfg.GPGKey != "" {
 				cfg.GPGKey = ""
 				if err := config.SaveConfig(cfg); err != nil {
-					fmt.Printf("⚠️ Ключ удален из GPG, но не удалось обновить конфиг: %v\n", err)
+					fmt.Printf("⚠️ Ключ удален, но не удалось обновить конфиг: %v\n", err)
 					os.Exit(1)
 				}
 			}
 
-			fmt.Printf("\n✅ Ключ %s успешно удален из GPG\n", keyID)
+			fmt.Printf("\n✅ Ключ %s успешно удален из %s\n", keyID, backend.Name())
 			fmt.Println("Файлы секретов и резервные копии сохранены в директории .secrets/")
 		},
 	}
@@ -134,130 +133,23 @@ func DeleteKeyCmd() *cobra.Command {
 	return cmd
 }
 
-type keyInfo struct {
-	name  string
-	email string
-}
-
-// autoDetectKey пытается автоматически определить ключ проекта
-func autoDetectKey() (string, error) {
-	cmd := exec.Command("gpg", "--list-secret-keys", "--keyid-format", "LONG")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("gpg error: %v", err)
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "sec") {
-			parts := strings.Fields(line)
-			if len(parts) >= 4 {
-				keyPart := parts[1]
-				if strings.Contains(keyPart, "/") {
-					keyParts := strings.Split(keyPart, "/")
-					if len(keyParts) == 2 {
-						return keyParts[1], nil
-					}
-				}
-			}
-		}
-	}
-
-	return "", fmt.Errorf("не удалось автоматически определить ключ")
-}
-
-// keyExistsInGPG проверяет, существует ли ключ в GPG
-func keyExistsInGPG(keyID string) bool {
-	cmd := exec.Command("gpg", "--list-keys", keyID)
-	err := cmd.Run()
-	return err == nil
-}
-
-func getKeyInfo(keyID string) (*keyInfo, error) {
-	cmd := exec.Command("gpg", "--list-keys", "--with-colons", keyID)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("gpg error: %v", err)
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "uid:") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 9 {
-				return &keyInfo{
-					name:  parts[9],
-					email: extractEmail(parts[9]),
-				}, nil
-			}
-		}
-	}
-
-	return nil, fmt.Errorf("не удалось разобрать информацию о ключе")
-}
-
-func extractEmail(uid string) string {
-	start := strings.Index(uid, "<")
-	end := strings.Index(uid, ">")
-	if start >= 0 && end > start {
-		return uid[start+1 : end]
-	}
-	return ""
-}
-
-// getFingerprint получает полный отпечаток ключа
-func getFingerprint(keyID string) (string, error) {
-	cmd := exec.Command("gpg", "--list-secret-keys", "--with-colons", keyID)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("gpg error: %v", err)
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "fpr:") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 9 {
-				return parts[9], nil // fpr:::::::::FINGERPRINT:
-			}
-		}
-	}
-
-	return "", fmt.Errorf("не удалось найти fingerprint для ключа %s", keyID)
-}
-
-func createBackup(cfg *config.Config, keyID string) error {
+func createBackup(cfg *config.Config, backend backends.Backend, keyID string) error {
 	backupDir := filepath.Join(".secrets", "backup")
 	if err := os.MkdirAll(backupDir, 0700); err != nil {
 		return fmt.Errorf("не удалось создать директорию: %v", err)
 	}
 
-	filenamePrefix := "key"
-	if cfg.ProjectName != "" {
-		filenamePrefix = strings.ToLower(strings.ReplaceAll(cfg.ProjectName, " ", "_"))
-	}
+	filenamePrefix := keyFilePrefix(cfg)
 
 	// Экспорт публичного ключа
 	pubKeyPath := filepath.Join(backupDir, fmt.Sprintf("%s.pub.asc", filenamePrefix))
-	cmdPub := exec.Command("gpg", "--armor", "--export", keyID)
-	pubOutput, err := cmdPub.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("экспорт публичного ключа: %s: %v", string(pubOutput), err)
-	}
-
-	if err := os.WriteFile(pubKeyPath, pubOutput, 0600); err != nil {
+	if err := exportKeyToFile(backend, keyID, false, pubKeyPath); err != nil {
 		return fmt.Errorf("не удалось сохранить публичный ключ: %v", err)
 	}
 
 	// Экспорт приватного ключа
 	privKeyPath := filepath.Join(backupDir, fmt.Sprintf("%s.priv.asc", filenamePrefix))
-	cmdPriv := exec.Command("gpg", "--armor", "--export-secret-keys", keyID)
-	privOutput, err := cmdPriv.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("экспорт приватного ключа: %s: %v", string(privOutput), err)
-	}
-
-	if err := os.WriteFile(privKeyPath, privOutput, 0600); err != nil {
+	if err := exportKeyToFile(backend, keyID, true, privKeyPath); err != nil {
 		return fmt.Errorf("не удалось сохранить приватный ключ: %v", err)
 	}
 
@@ -267,48 +159,6 @@ func createBackup(cfg *config.Config, keyID string) error {
 	return nil
 }
 
-func deleteKey(fingerprint string) error {
-	// Удаляем приватный ключ в batch с fingerprint
-	cmdDelSecret := exec.Command("gpg", "--batch", "--yes", "--delete-secret-keys", fingerprint)
-	cmdDelSecret.Stdin = os.Stdin
-	cmdDelSecret.Stdout = os.Stdout
-	cmdDelSecret.Stderr = os.Stderr
-
-	if err := cmdDelSecret.Run(); err != nil {
-		// Если batch не сработал, пробуем интерактивно с fingerprint
-		fmt.Println("⚠️  Не удалось удалить приватный ключ в batch режиме, пробуем интерактивно...")
-		cmdDelSecret = exec.Command("gpg", "--delete-secret-keys", fingerprint)
-		cmdDelSecret.Stdin = os.Stdin
-		cmdDelSecret.Stdout = os.Stdout
-		cmdDelSecret.Stderr = os.Stderr
-
-		if err := cmdDelSecret.Run(); err != nil {
-			return fmt.Errorf("не удалось удалить приватный ключ: %v", err)
-		}
-	}
-
-	// Удаляем публичный ключ в batch с fingerprint
-	cmdDelPub := exec.Command("gpg", "--batch", "--yes", "--delete-keys", fingerprint)
-	cmdDelPub.Stdin = os.Stdin
-	cmdDelPub.Stdout = os.Stdout
-	cmdDelPub.Stderr = os.Stderr
-
-	if err := cmdDelPub.Run(); err != nil {
-		// Если batch не сработал, пробуем интерактивно с fingerprint
-		fmt.Println("⚠️  Не удалось удалить публичный ключ в batch режиме, пробуем интерактивно...")
-		cmdDelPub = exec.Command("gpg", "--delete-keys", fingerprint)
-		cmdDelPub.Stdin = os.Stdin
-		cmdDelPub.Stdout = os.Stdout
-		cmdDelPub.Stderr = os.Stderr
-
-		if err := cmdDelPub.Run(); err != nil {
-			return fmt.Errorf("не удалось удалить публичный ключ: %v", err)
-		}
-	}
-
-	return nil
-}
-
 func printManualDeleteInstructions(fingerprint string) {
 	fmt.Println("\nПопробуйте выполнить следующие команды вручную (используйте полный fingerprint):")
 	fmt.Println()
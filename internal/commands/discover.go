@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// credentialPatterns — известные форматы учетных данных
+var credentialPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"приватный ключ", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY(?: BLOCK)?-----`)},
+	{"ключ AWS", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"JWT", regexp.MustCompile(`\beyJ[\w-]{8,}\.eyJ[\w-]{8,}\.[\w-]{8,}`)},
+	{"токен GitHub", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"токен Slack", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"строка подключения с паролем", regexp.MustCompile(`\b[a-zA-Z][\w+.-]*://[^\s:/@'"]+:([^\s/@'"]+)@[\w.-]+`)},
+}
+
+// Пароль в строке подключения, который не является секретом
+var placeholderPassword = regexp.MustCompile(`(?i)^(?:[$<{*%].*|password|pass|secret|changeme|xxx+)$`)
+
+var entropyToken = regexp.MustCompile(`[A-Za-z0-9+/_-]{20,}`)
+
+// Файлы с хешами зависимостей: высокая энтропия, но не секреты
+var lockFiles = map[string]bool{
+	"go.sum": true, "package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"Cargo.lock": true, "poetry.lock": true, "Gemfile.lock": true, "composer.lock": true,
+}
+
+// Директории зависимостей и служебные директории, которые не сканируются
+var vendorDirs = map[string]bool{
+	".git": true, ".secret": true, "vendor": true, "node_modules": true,
+	"third_party": true, ".venv": true, "venv": true, "__pycache__": true, ".terraform": true,
+}
+
+// secretFileNames — шаблоны имен файлов, которые обычно содержат секреты
+var secretFileNames = []string{
+	".env", ".env.*", "*.env", "*.pem", "*.key", "credentials*.json",
+	"*.tfvars", "*.tfvars.json", "kubeconfig*", "*.kubeconfig",
+}
+
+// fileCandidate — файл, предложенный для secret_files
+type fileCandidate struct {
+	path    string
+	note    string
+	checked bool
+}
+
+// findSecretFiles ищет в проекте файлы с типичными для секретов именами
+// (отмечены) и файлы, в содержимом которых найдены учетные данные (не отмечены).
+// Директории зависимостей и исключенные в .gitignore директории пропускаются.
+func findSecretFiles(ext string) []fileCandidate {
+	ignoredDirs := gitIgnoredDirs()
+	seen := map[string]bool{}
+	var candidates []fileCandidate
+	_ = filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		slashed := filepath.ToSlash(path)
+		if d.IsDir() {
+			if path != "." && (vendorDirs[d.Name()] || ignoredDirs[slashed+"/"]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !secretFileName(slashed, ext) {
+			return nil
+		}
+		if content, err := os.ReadFile(path); err != nil || backends.LooksEncrypted(content) {
+			return nil
+		}
+		seen[slashed] = true
+		candidates = append(candidates, fileCandidate{path: slashed, checked: true})
+		return nil
+	})
+
+	for _, f := range discoverSecrets(&config.Config{}, ext) {
+		slashed := filepath.ToSlash(f.file)
+		if !seen[slashed] {
+			seen[slashed] = true
+			candidates = append(candidates, fileCandidate{path: slashed, note: f.kind})
+		}
+	}
+	return candidates
+}
+
+// secretFileName сообщает, похоже ли имя файла на файл с секретами.
+// Примеры, шаблоны и шифротекст не предлагаются.
+func secretFileName(path, ext string) bool {
+	base := filepath.Base(path)
+	lower := strings.ToLower(base)
+	if strings.HasSuffix(path, ext) || strings.Contains(lower, "example") || strings.Contains(lower, "sample") || strings.Contains(lower, "template") {
+		return false
+	}
+	if strings.HasSuffix(path, ".kube/config") {
+		return true
+	}
+	for _, pattern := range secretFileNames {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// gitIgnoredDirs возвращает директории, исключенные в .gitignore (с "/" в конце)
+func gitIgnoredDirs() map[string]bool {
+	dirs := map[string]bool{}
+	out, err := gitOutput("ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil {
+		return dirs
+	}
+	for _, path := range strings.Split(string(out), "\x00") {
+		if strings.HasSuffix(path, "/") {
+			dirs[path] = true
+		}
+	}
+	return dirs
+}
+
+// finding — строка файла, похожая на секрет
+type finding struct {
+	file string
+	line int
+	kind string
+}
+
+// discoverSecrets ищет в файлах проекта, не входящих в secret_files,
+// известные форматы учетных данных и строки с высокой энтропией.
+// Возвращает по одной находке на строку в порядке файлов.
+func discoverSecrets(cfg *config.Config, ext string) []finding {
+	files := workTreeFiles()
+	sort.Strings(files)
+	var findings []finding
+	for _, file := range files {
+		if skipDiscover(cfg, ext, file) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil || isBinary(content) || backends.LooksEncrypted(content) {
+			continue
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			if kind := detectSecret(line); kind != "" {
+				findings = append(findings, finding{file: file, line: i + 1, kind: kind})
+			}
+		}
+	}
+	return findings
+}
+
+// skipDiscover отбрасывает файлы, которые уже защищены или не содержат секретов
+func skipDiscover(cfg *config.Config, ext, file string) bool {
+	base := filepath.Base(file)
+	if lockFiles[base] || strings.Contains(base, ".example.") || strings.HasSuffix(file, ext) {
+		return true
+	}
+	slashed := filepath.ToSlash(file)
+	if strings.HasPrefix(slashed, ".secret/") || strings.HasPrefix(slashed, ".git/") {
+		return true
+	}
+	if info, err := os.Stat(file); err != nil || info.Size() > 1<<20 {
+		return true
+	}
+	return matchesSecretFiles(cfg, file)
+}
+
+// detectSecret возвращает вид секрета, найденного в строке, или ""
+func detectSecret(line string) string {
+	for _, p := range credentialPatterns {
+		m := p.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if len(m) > 1 && placeholderPassword.MatchString(m[1]) {
+			continue
+		}
+		return p.name
+	}
+	// Энтропия проверяется только у значений, а не у идентификаторов в коде:
+	// токен должен стоять после =, : или открывающей кавычки
+	for _, loc := range entropyToken.FindAllStringIndex(line, -1) {
+		prefix := strings.TrimRight(line[:loc[0]], " \t")
+		if prefix != "" && strings.ContainsAny(prefix[len(prefix)-1:], "=:\"'`") && highEntropy(line[loc[0]:loc[1]]) {
+			return "строка с высокой энтропией"
+		}
+	}
+	return ""
+}
+
+// highEntropy отличает случайные токены (ключи API, base64) от слов и
+// путей: нужны заглавные и строчные буквы и цифры, а энтропия Шеннона —
+// близкая к максимальной для такой длины
+func highEntropy(token string) bool {
+	var upper, lower, digits int
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+		case c >= 'a' && c <= 'z':
+			lower++
+		case c >= '0' && c <= '9':
+			digits++
+		}
+	}
+	if upper < 2 || lower < 2 || digits < 2 {
+		return false
+	}
+	return shannonEntropy(token) >= 0.8*math.Log2(math.Min(float64(len(token)), 64))
+}
+
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, c := range s {
+		counts[c]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
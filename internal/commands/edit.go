@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/spf13/cobra"
+)
+
+// @ edit cmd
+func EditCmd() *cobra.Command {
+	var env string
+
+	cmd := &cobra.Command{
+		Use:   "edit <file.gpg>",
+		Short: "Открывает расшифрованный файл в редакторе",
+		Long: `Расшифровывает файл во временную директорию (tmpfs /dev/shm, если есть),
+открывает его в $VISUAL или $EDITOR и шифрует заново, если содержимое
+изменилось. Временная копия затирается и удаляется даже при ошибке
+редактора или прерывании (Ctrl+C).
+Пример:
+  EDITOR="code --wait" secret edit .env.gpg`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, backend, err := loadEnvBackend(env)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if err := editFile(backend, plainName(backend, args[0])); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Окружение из environments")
+	return cmd
+}
+
+// editFile расшифровывает file во временную копию, запускает редактор
+// и шифрует результат. Копия удаляется при любом исходе.
+func editFile(backend backends.Backend, file string) error {
+	// Перехватываем сигналы, чтобы не оставить открытую копию на диске
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	original, err := backend.DecryptBytes(file + backend.Ext())
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp(privateTempDir(), "secret-edit-*")
+	if err != nil {
+		return err
+	}
+	// Имя файла сохраняем, чтобы редактор подсветил синтаксис
+	tmpFile := filepath.Join(dir, filepath.Base(file))
+	defer wipeDir(dir, tmpFile)
+
+	if err := os.WriteFile(tmpFile, original, 0600); err != nil {
+		return err
+	}
+
+	editor := editorCommand()
+	if code := runWithEnv(append(editor, tmpFile), os.Environ()); code != 0 {
+		return fmt.Errorf("редактор %s завершился с кодом %d, файл не изменен", editor[0], code)
+	}
+	select {
+	case sig := <-signals:
+		return fmt.Errorf("прервано сигналом %v, файл не изменен", sig)
+	default:
+	}
+
+	edited, err := os.ReadFile(tmpFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(original, edited) {
+		fmt.Println("ℹ️ Изменений нет, файл не перешифрован")
+		return nil
+	}
+	if err := backend.EncryptBytes(file, edited); err != nil {
+		return fmt.Errorf("ошибка шифрования: %v", err)
+	}
+
+	fmt.Printf("✅ Файл %s обновлен\n", file+backend.Ext())
+	if _, err := os.Stat(file); err == nil {
+		fmt.Printf("⚠️ Открытый файл %s устарел. Выполните: secret decrypt\n", file)
+	}
+	return nil
+}
+
+// privateTempDir возвращает tmpfs (в памяти), если он доступен
+func privateTempDir() string {
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "/dev/shm"
+	}
+	return os.TempDir()
+}
+
+// editorCommand возвращает команду редактора из $VISUAL или $EDITOR.
+// Аргументы поддерживаются: EDITOR="code --wait".
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(env)); len(editor) > 0 {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// wipeDir затирает временную копию нулями и удаляет директорию
+// вместе с резервными файлами редактора
+func wipeDir(dir, file string) {
+	if info, err := os.Stat(file); err == nil {
+		if f, err := os.OpenFile(file, os.O_WRONLY, 0); err == nil {
+			_, _ = f.Write(make([]byte, info.Size()))
+			_ = f.Sync()
+			f.Close()
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		fmt.Printf("⚠️ Не удалось удалить временную копию %s: %v\n", dir, err)
+	}
+}
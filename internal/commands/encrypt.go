@@ -5,7 +5,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/Avdushin/secret/internal/backends"
 	"github.com/Avdushin/secret/pkg/config"
 	"github.com/spf13/cobra"
 )
@@ -14,36 +13,42 @@ import (
 func EncryptCmd() *cobra.Command {
 	var keyID string
 	var allFiles bool
+	var env string
 
 	cmd := &cobra.Command{
 		Use:   "encrypt [file]",
 		Short: "Шифрует конфигурационные файлы",
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			cfg, err := config.LoadConfig()
+			cfg, backend, err := loadEnvBackend(env)
 			if err != nil {
-				fmt.Printf("Ошибка загрузки конфига: %v\n", err)
+				fmt.Println(err)
 				os.Exit(1)
 			}
 
-			// Если ключ указан явно, временно переопределяем
+			// Если ключ указан явно, шифруем только для него: recipients
+			// и группы файлов из конфига в этом запуске не используются
 			if keyID != "" {
+				if len(cfg.Recipients) > 0 {
+					fmt.Printf("🔑 Шифруем только для ключа %s (recipients из конфига игнорируются)\n", keyID)
+				}
 				cfg.GPGKey = keyID
+				cfg.Recipients = nil
+				cfg.SecretFiles = config.NewSecretFiles(cfg.SecretFiles.Patterns())
 			}
 
-			gpg := backends.NewGPGBackend(cfg)
-
 			// Если указан конкретный файл
 			if len(args) == 1 {
-				if err := gpg.Encrypt(args[0]); err != nil {
+				if err := backend.Encrypt(args[0]); err != nil {
 					fmt.Printf("❌ Ошибка: %v\n", err)
 					os.Exit(1)
 				}
+				updateGitignore(backend.Ext(), args[0])
 				return
 			}
 
 			// Шифруем все файлы из конфига
-			filesToEncrypt := getFilesToProcess(cfg.SecretFiles)
+			filesToEncrypt := getFilesToProcess(cfg.SecretFiles.Patterns())
 			if len(filesToEncrypt) == 0 {
 				fmt.Println("ℹ️ Не найдено файлов для шифрования")
 				return
@@ -51,17 +56,19 @@ func EncryptCmd() *cobra.Command {
 
 			fmt.Printf("🔒 Шифруем %d файлов...\n", len(filesToEncrypt))
 			for _, file := range filesToEncrypt {
-				if err := gpg.Encrypt(file); err != nil {
+				if err := backend.Encrypt(file); err != nil {
 					fmt.Printf("⚠️ Ошибка при шифровании %s: %v\n", file, err)
 				}
 			}
+			updateGitignore(backend.Ext())
 
 			fmt.Println("✅ Все файлы обработаны")
 		},
 	}
 
-	cmd.Flags().StringVarP(&keyID, "key", "k", "", "GPG Key ID для шифрования")
+	cmd.Flags().StringVarP(&keyID, "key", "k", "", "Ключ для шифрования вместо recipients из конфига")
 	cmd.Flags().BoolVarP(&allFiles, "all", "a", false, "Шифровать все файлы из конфига")
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Окружение из environments (только его ключ и файлы)")
 	return cmd
 }
 
@@ -3,11 +3,9 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
-	"github.com/Avdushin/secret/pkg/config"
+	"github.com/Avdushin/secret/internal/backends"
 	"github.com/spf13/cobra"
 )
 
@@ -17,16 +15,19 @@ func ExportKeyCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "export",
-		Short: "Экспортирует GPG-ключ проекта",
+		Short: "Экспортирует ключ проекта",
+		Long: `Экспортирует публичную и приватную части ключа проекта для резервной копии.
+Для добавления участника передавать приватный ключ не нужно: новый участник
+выполняет secret join, а участник с доступом — secret members approve.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			cfg, err := config.LoadConfig()
+			cfg, backend, err := loadBackend()
 			if err != nil {
-				fmt.Printf("Ошибка загрузки конфига: %v\n", err)
+				fmt.Println(err)
 				os.Exit(1)
 			}
 
 			if cfg.GPGKey == "" {
-				fmt.Println("❌ В проекте не настроен GPG-ключ")
+				fmt.Println("❌ В проекте не настроен ключ")
 				fmt.Println("Сначала выполните: secret init")
 				os.Exit(1)
 			}
@@ -41,35 +42,39 @@ func ExportKeyCmd() *cobra.Command {
 			}
 
 			// Формируем имя файла с именем проекта
-			filenamePrefix := "key"
-			if cfg.ProjectName != "" {
-				filenamePrefix = strings.ToLower(strings.ReplaceAll(cfg.ProjectName, " ", "_"))
-			}
+			filenamePrefix := keyFilePrefix(cfg)
 
 			// Экспортируем публичный ключ
 			pubKeyPath := filepath.Join(outputDir, fmt.Sprintf("%s.pub.asc", filenamePrefix))
-			cmdPub := exec.Command("gpg", "--output", pubKeyPath, "--armor", "--export", cfg.GPGKey)
-			if output, err := cmdPub.CombinedOutput(); err != nil {
-				fmt.Printf("Ошибка экспорта публичного ключа: %s\n", output)
+			if err := exportKeyToFile(backend, cfg.GPGKey, false, pubKeyPath); err != nil {
+				fmt.Printf("Ошибка экспорта публичного ключа: %v\n", err)
 				os.Exit(1)
 			}
 
 			// Экспортируем приватный ключ
 			privKeyPath := filepath.Join(outputDir, fmt.Sprintf("%s.priv.asc", filenamePrefix))
-			cmdPriv := exec.Command("gpg", "--output", privKeyPath, "--armor", "--export-secret-keys", cfg.GPGKey)
-			cmdPriv.Stdin = os.Stdin // Для ввода пароля если нужно
-			if output, err := cmdPriv.CombinedOutput(); err != nil {
-				fmt.Printf("Ошибка экспорта приватного ключа: %s\n", output)
+			if err := exportKeyToFile(backend, cfg.GPGKey, true, privKeyPath); err != nil {
+				fmt.Printf("Ошибка экспорта приватного ключа: %v\n", err)
 				os.Exit(1)
 			}
 
 			fmt.Printf("\n✅ Ключи экспортированы в %s:\n", outputDir)
 			fmt.Printf(" - Публичный ключ: %s\n", pubKeyPath)
 			fmt.Printf(" - Приватный ключ: %s\n", privKeyPath)
-			fmt.Println("\n⚠️ Безопасно передайте приватный ключ другим участникам проекта!")
+			fmt.Println("\n⚠️ Храните приватный ключ как личную резервную копию и никому не передавайте")
+			fmt.Println("Новые участники получают доступ через: secret join + secret members approve")
 		},
 	}
 
 	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "Директория для экспорта (по умолчанию .secrets/backup)")
 	return cmd
 }
+
+// exportKeyToFile экспортирует ключ через бэкенд и сохраняет его в файл
+func exportKeyToFile(backend backends.Backend, keyID string, secret bool, path string) error {
+	data, err := backend.ExportKey(keyID, secret)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/internal/fields"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// @ get cmd
+func GetCmd() *cobra.Command {
+	var env string
+
+	cmd := &cobra.Command{
+		Use:   "get <file> <key>",
+		Short: "Выводит одно значение из зашифрованного файла",
+		Long: `Расшифровывает файл в память и выводит значение ключа в stdout.
+Поддерживаются .env, JSON и YAML; для JSON и YAML ключ задается путем
+через точку.
+Примеры:
+  secret get .env DB_PASSWORD
+  secret get config.yaml database.password`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, backend, err := loadEnvBackend(env)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			file, key := plainName(backend, args[0]), args[1]
+			content, err := backend.DecryptBytes(file + backend.Ext())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Ошибка: %v\n", err)
+				os.Exit(1)
+			}
+			value, err := fields.Get(fields.DetectFormat(file), content, key)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %s: %v\n", file, err)
+				os.Exit(1)
+			}
+			fmt.Println(value)
+		},
+	}
+
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Окружение из environments")
+	return cmd
+}
+
+// @ set cmd
+func SetCmd() *cobra.Command {
+	var env string
+
+	cmd := &cobra.Command{
+		Use:   "set <file> <key>",
+		Short: "Изменяет одно значение в зашифрованном файле",
+		Long: `Расшифровывает файл в память, записывает значение ключа, шифрует файл
+заново и обновляет .example. Открытый текст на диск не записывается.
+Значение вводится без эха или читается из stdin.
+Если зашифрованного файла еще нет, он будет создан.
+Примеры:
+  secret set .env DB_PASSWORD
+  echo -n "$TOKEN" | secret set config.yaml api.token`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, backend, err := loadEnvBackend(env)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			file, key := plainName(backend, args[0]), args[1]
+			readValue := func() (string, error) {
+				return readSecretValue(fmt.Sprintf("Значение %s: ", key))
+			}
+			if err := setValue(backend, file, key, readValue); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("✅ %s обновлен в %s\n", key, file+backend.Ext())
+			if _, err := os.Stat(file); err == nil {
+				fmt.Printf("⚠️ Открытый файл %s устарел. Выполните: secret decrypt\n", file)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&env, "env", "e", "", "Окружение из environments")
+	return cmd
+}
+
+// setValue записывает значение ключа key в зашифрованный файл file.
+// Значение запрашивается через readValue только после проверки формата
+// и расшифровки: секрет не приходится вводить ради ошибки.
+func setValue(backend backends.Backend, file, key string, readValue func() (string, error)) error {
+	format := fields.DetectFormat(file)
+	if !format.Editable() {
+		return fmt.Errorf("%s: формат файла не поддерживается (доступны: env, json, yaml)", file)
+	}
+
+	var content []byte
+	if _, err := os.Stat(file + backend.Ext()); err == nil {
+		if content, err = backend.DecryptBytes(file + backend.Ext()); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(file); err == nil {
+		// Иначе значения из открытого файла молча пропадут
+		return fmt.Errorf("файл %s еще не зашифрован. Выполните: secret encrypt %s", file, file)
+	}
+
+	value, err := readValue()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения значения: %v", err)
+	}
+	updated, err := fields.Set(format, content, key, value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if err := backend.EncryptBytes(file, updated); err != nil {
+		return fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	return nil
+}
+
+// plainName убирает расширение бэкенда, если передано имя зашифрованного файла
+func plainName(backend backends.Backend, file string) string {
+	return strings.TrimSuffix(file, backend.Ext())
+}
+
+// readSecretValue запрашивает значение без эха или читает весь stdin,
+// если он перенаправлен (завершающий перевод строки отбрасывается)
+func readSecretValue(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		value := promptPassword(prompt)
+		if value == "" {
+			return "", fmt.Errorf("пустое значение")
+		}
+		return value, nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
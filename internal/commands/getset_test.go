@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetValueUnsupportedFormat(t *testing.T) {
+	_, b := newTestProject(t)
+	for _, file := range []string{"config.toml", "app.ini", "notes.txt"} {
+		encryptTestFile(t, b, file, "a = 1\n")
+		prompted := false
+		err := setValue(b, file, "a", func() (string, error) {
+			prompted = true
+			return "secret", nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "не поддерживается") {
+			t.Errorf("%s: err = %v, want unsupported format", file, err)
+		}
+		if prompted {
+			t.Errorf("%s: value requested before format check", file)
+		}
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	_, b := newTestProject(t)
+	encryptTestFile(t, b, ".env", "A=1\nB=2\n")
+	if err := setValue(b, ".env", "B", func() (string, error) { return "new value", nil }); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.DecryptBytes(".env.gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A=1\nB=\"new value\"\n"; string(got) != want {
+		t.Errorf("decrypted = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,558 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/internal/fields"
+	"github.com/Avdushin/secret/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// gitFilter — имя фильтра и diff-драйвера в git config и .gitattributes
+const gitFilter = "secret"
+
+// Метки блока, которым secret управляет в .gitattributes и .gitignore
+const (
+	managedBegin = "# >>> secret >>>"
+	managedEnd   = "# <<< secret <<<"
+)
+
+// @ git cmd
+func GitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git",
+		Short: "Прозрачное шифрование файлов в git",
+		Long: `Подключает secret к git как фильтр clean/smudge (как git-crypt):
+в рабочей директории лежат открытые файлы, а в репозиторий попадает
+шифротекст. Запускать secret encrypt перед каждым коммитом не нужно.
+  secret git install`,
+	}
+
+	cmd.AddCommand(gitInstallCmd())
+	cmd.AddCommand(gitCleanCmd())
+	cmd.AddCommand(gitSmudgeCmd())
+	cmd.AddCommand(gitTextconvCmd())
+	cmd.AddCommand(gitMergeCmd())
+	return cmd
+}
+
+func gitInstallCmd() *cobra.Command {
+	var mask bool
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Регистрирует фильтр secret в git и .gitattributes",
+		Long: `Записывает фильтр, diff- и merge-драйверы secret в .git/config
+репозитория и добавляет в .gitattributes правила filter, diff и merge для
+всех secret_files (включая файлы окружений) и их зашифрованных копий.
+Команду нужно выполнить в каждом клоне репозитория.
+С --mask git diff показывает вместо значений их короткие хеши.
+Работает только с бэкендами, которые хранят шифротекст в репозитории
+(gpg, openpgp, age): vault и bitwarden хранят в git лишь указатели,
+и каждый git add создавал бы новую запись во внешнем хранилище.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if _, err := gitOutput("rev-parse", "--show-toplevel"); err != nil {
+				fmt.Println("❌ Текущая директория не является git-репозиторием")
+				os.Exit(1)
+			}
+			// Фильтр шифрует содержимое в памяти: бэкенду нужен Seal
+			if _, err := backend.Seal(".env", nil); errors.Is(err, backends.ErrNotSupported) {
+				fmt.Printf("❌ Бэкенд %s хранит секреты во внешнем сервисе и не поддерживает фильтр git\n", backend.Name())
+				fmt.Println("Используйте secret encrypt и коммитьте файлы указателей")
+				os.Exit(1)
+			}
+
+			self, err := os.Executable()
+			if err != nil {
+				fmt.Printf("❌ Не удалось определить путь к secret: %v\n", err)
+				os.Exit(1)
+			}
+			textconv := fmt.Sprintf("%q git textconv", self)
+			if mask {
+				textconv += " --mask"
+			}
+			settings := [][2]string{
+				{"filter." + gitFilter + ".clean", fmt.Sprintf("%q git clean %%f", self)},
+				{"filter." + gitFilter + ".smudge", fmt.Sprintf("%q git smudge %%f", self)},
+				{"filter." + gitFilter + ".required", "true"},
+				// Кэш textconv сохранил бы открытый текст в git notes
+				{"diff." + gitFilter + ".textconv", textconv},
+				{"diff." + gitFilter + ".cachetextconv", "false"},
+				{"merge." + gitFilter + ".name", "secret: слияние зашифрованных файлов по ключам"},
+				{"merge." + gitFilter + ".driver", fmt.Sprintf("%q git merge %%O %%A %%B %%P", self)},
+			}
+			for _, s := range settings {
+				if _, err := gitOutput("config", "--local", s[0], s[1]); err != nil {
+					fmt.Printf("❌ Ошибка записи git config %s: %v\n", s[0], err)
+					os.Exit(1)
+				}
+			}
+
+			patterns := allSecretPatterns(cfg)
+			if err := writeManagedBlock(".gitattributes", gitAttributes(patterns, backend.Ext())); err != nil {
+				fmt.Printf("❌ Ошибка записи .gitattributes: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Println("✅ Фильтр secret зарегистрирован в git")
+			// Открытые secret_files больше не нужно исключать в .gitignore
+			updateGitignore(backend.Ext())
+			fmt.Printf("📝 .gitattributes: %d шаблонов файлов\n", len(patterns))
+
+			// Открытые файлы теперь коммитятся сами, git хранит шифротекст
+			if ignored := gitIgnoredFiles(getFilesToProcess(patterns)); len(ignored) > 0 {
+				fmt.Println("\n⚠️ Эти файлы исключены в .gitignore и не попадут в репозиторий:")
+				for _, f := range ignored {
+					fmt.Printf("  - %s\n", f)
+				}
+				fmt.Println("Уберите их из .gitignore, чтобы git хранил их шифротекст.")
+			}
+			fmt.Println("\nДобавьте файлы и закоммитьте .gitattributes:")
+			fmt.Println("  git add .gitattributes && git add --renormalize .")
+		},
+	}
+
+	cmd.Flags().BoolVar(&mask, "mask", false, "В git diff показывать хеши значений вместо самих значений")
+	return cmd
+}
+
+func gitCleanCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clean <file>",
+		Short: "Фильтр git: шифрует stdin в stdout",
+		Long: `Вызывается git при добавлении файла в индекс. Если открытый текст
+не изменился, возвращает шифротекст из индекса, чтобы git status и
+git diff не показывали изменений из-за случайности шифрования.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			out := filterOutput()
+			if err := gitClean(args[0], os.Stdin, out); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ secret git clean %s: %v\n", args[0], err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func gitSmudgeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "smudge <file>",
+		Short: "Фильтр git: расшифровывает stdin в stdout",
+		Long: `Вызывается git при checkout. Если расшифровать файл нельзя
+(нет доступа), в рабочую директорию попадает шифротекст, а checkout
+не прерывается.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			out := filterOutput()
+			if err := gitSmudge(args[0], os.Stdin, out); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ secret git smudge %s: %v\n", args[0], err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func gitTextconvCmd() *cobra.Command {
+	var mask bool
+
+	cmd := &cobra.Command{
+		Use:   "textconv <file>",
+		Short: "Драйвер git diff: выводит расшифрованный файл",
+		Long: `Вызывается git diff и git log -p для файлов с diff=secret.
+Выводит расшифрованное содержимое (с --mask — хеши вместо значений),
+а при отсутствии доступа — строку о том, что файл не расшифрован.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			out := filterOutput()
+			if err := gitTextconv(args[0], mask, out); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ secret git textconv: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&mask, "mask", false, "Показывать короткие хеши вместо значений")
+	return cmd
+}
+
+func gitMergeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge <base> <ours> <theirs> <path>",
+		Short: "Драйвер git merge: слияние зашифрованных файлов по ключам",
+		Long: `Вызывается git при слиянии файлов с merge=secret. Расшифровывает три
+версии, сливает .env, JSON и YAML по ключам и шифрует результат.
+Конфликт возникает, только если один ключ изменен в обеих ветках.`,
+		Args: cobra.ExactArgs(4),
+		Run: func(cmd *cobra.Command, args []string) {
+			filterOutput()
+			conflicts, err := gitMerge(args[0], args[1], args[2], args[3])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ secret git merge %s: %v\n", args[3], err)
+				os.Exit(1)
+			}
+			if len(conflicts) > 0 {
+				fmt.Fprintf(os.Stderr, "⚠️ secret: конфликт в %s, ключи изменены в обеих ветках:\n", args[3])
+				for _, key := range conflicts {
+					fmt.Fprintf(os.Stderr, "  - %s\n", key)
+				}
+				fmt.Fprintf(os.Stderr, "В файле оставлены текущие значения. Вторая сторона: git diff MERGE_HEAD -- %s\n", args[3])
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// gitClean шифрует открытый текст файла file для хранения в git
+func gitClean(file string, in io.Reader, out io.Writer) error {
+	plaintext, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	_, backend, err := loadFileBackend(file)
+	if err != nil {
+		return err
+	}
+
+	staged, cleanup, err := stageFile(file)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Открытый текст в индексе (файл закоммичен до secret git install)
+	// не переиспользуется: его нужно зашифровать
+	if blob, err := gitOutput("cat-file", "blob", ":"+filepath.ToSlash(file)); err == nil && backends.LooksEncrypted(blob) {
+		// В рабочей директории шифротекст (smudge без доступа)
+		if bytes.Equal(blob, plaintext) {
+			_, err := out.Write(blob)
+			return err
+		}
+		// Старый шифротекст нужен и для mode: values — ключ данных переиспользуется
+		if err := os.WriteFile(staged+backend.Ext(), blob, 0600); err != nil {
+			return err
+		}
+		if old, err := backend.DecryptBytes(staged + backend.Ext()); err == nil && bytes.Equal(old, plaintext) {
+			_, err := out.Write(blob)
+			return err
+		}
+	}
+
+	if err := backend.EncryptBytes(staged, plaintext); err != nil {
+		return err
+	}
+	ciphertext, err := os.ReadFile(staged + backend.Ext())
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(ciphertext)
+	return err
+}
+
+// gitSmudge расшифровывает шифротекст из git в рабочую директорию
+func gitSmudge(file string, in io.Reader, out io.Writer) error {
+	ciphertext, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext) == 0 {
+		return nil
+	}
+	_, backend, err := loadFileBackend(file)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := decryptBlob(backend, file, ciphertext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️ secret: нет доступа к %s, в рабочую директорию записан шифротекст (%v)\n", file, err)
+		plaintext = ciphertext
+	}
+	_, err = out.Write(plaintext)
+	return err
+}
+
+// gitTextconv выводит расшифрованное содержимое файла для git diff.
+// Ошибка расшифровки не прерывает diff, а попадает в вывод строкой.
+func gitTextconv(file string, mask bool, out io.Writer) error {
+	cfg, backend, err := loadBackend()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if len(content) == 0 {
+		return nil
+	}
+
+	// Для файлов с filter=secret git передает уже расшифрованное smudge содержимое
+	plaintext := content
+	if backends.LooksEncrypted(content) {
+		if plaintext, err = backend.DecryptBytes(file); err != nil {
+			_, err = fmt.Fprintf(out, "[secret: не удалось расшифровать (%d байт шифротекста, %s) — нет доступа к ключу]\n", len(content), shortHash(content))
+			return err
+		}
+	}
+	if mask {
+		plaintext = maskValues(cfg, strings.TrimSuffix(file, backend.Ext()), plaintext)
+	}
+	_, err = out.Write(plaintext)
+	return err
+}
+
+// gitMerge сливает версии base, ours и theirs файла path и записывает
+// зашифрованный результат в ours. Возвращает конфликтующие ключи.
+func gitMerge(base, ours, theirs, path string) ([]string, error) {
+	_, backend, err := loadFileBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	// Для .env.gpg правила доступа берутся по открытому имени
+	file := plainName(backend, path)
+	if file != path {
+		if _, backend, err = loadFileBackend(file); err != nil {
+			return nil, err
+		}
+	}
+	format := fields.DetectFormat(file)
+	if format != fields.Env && format != fields.JSON && format != fields.YAML {
+		return nil, fmt.Errorf("формат %s не поддерживает слияние по ключам (доступны: env, json, yaml)", format)
+	}
+
+	staged, cleanup, err := stageFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// ours расшифровывается последним: его шифротекст остается в staged,
+	// и mode: values переиспользует ключ данных
+	paths := [3]string{base, theirs, ours}
+	labels := [3]string{"base", "theirs", "ours"}
+	var versions [3][]byte
+	for i, name := range paths {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if backends.LooksEncrypted(content) {
+			if err := os.WriteFile(staged+backend.Ext(), content, 0600); err != nil {
+				return nil, err
+			}
+			if content, err = backend.DecryptBytes(staged + backend.Ext()); err != nil {
+				return nil, fmt.Errorf("не удалось расшифровать версию %s: %v", labels[i], err)
+			}
+		}
+		versions[i] = content
+	}
+
+	merged, conflicts, err := fields.Merge(format, versions[0], versions[2], versions[1])
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.EncryptBytes(staged, merged); err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	ciphertext, err := os.ReadFile(staged + backend.Ext())
+	if err != nil {
+		return nil, err
+	}
+	return conflicts, os.WriteFile(ours, ciphertext, 0600)
+}
+
+// maskValues заменяет секретные значения короткими хешами: diff показывает,
+// какие ключи изменились, но не сами значения
+func maskValues(cfg *config.Config, file string, plaintext []byte) []byte {
+	format := fields.DetectFormat(file)
+	values, err := fields.Scan(format, plaintext)
+	if err != nil {
+		return []byte(fmt.Sprintf("[secret: содержимое скрыто, %s]\n", shortHash(plaintext)))
+	}
+	return fields.Replace(plaintext, values, func(v fields.Value, raw []byte) []byte {
+		if !cfg.Markers.Marked(v.Key, v.Comment) {
+			return raw
+		}
+		return []byte("<" + shortHash(raw) + ">")
+	})
+}
+
+func shortHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:4])
+}
+
+// stageFile создает временную директорию и возвращает путь в ней с тем же
+// относительным путем, что у file: от него зависят правила доступа secret_files
+func stageFile(file string) (string, func(), error) {
+	work, err := os.MkdirTemp("", "secret-git-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(work) }
+	staged := filepath.Join(work, file)
+	if err := os.MkdirAll(filepath.Dir(staged), 0700); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return staged, cleanup, nil
+}
+
+// decryptBlob расшифровывает шифротекст файла file из git
+func decryptBlob(backend backends.Backend, file string, ciphertext []byte) ([]byte, error) {
+	staged, cleanup, err := stageFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	if err := os.WriteFile(staged+backend.Ext(), ciphertext, 0600); err != nil {
+		return nil, err
+	}
+	return backend.DecryptBytes(staged + backend.Ext())
+}
+
+// loadFileBackend создает бэкенд для окружения, к которому относится файл
+func loadFileBackend(file string) (*config.Config, backends.Backend, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки конфига: %v", err)
+	}
+	if cfg.SecretFiles.Find(file) != nil {
+		return loadEnvBackend("")
+	}
+	for _, name := range sortedEnvironments(cfg) {
+		if cfg.Environments[name].SecretFiles.Find(file) != nil {
+			return loadEnvBackend(name)
+		}
+	}
+	return loadEnvBackend("")
+}
+
+// allSecretPatterns возвращает шаблоны secret_files основного конфига и всех окружений
+func allSecretPatterns(cfg *config.Config) []string {
+	seen := map[string]bool{}
+	var patterns []string
+	add := func(list []string) {
+		for _, p := range list {
+			if !seen[p] {
+				seen[p] = true
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	add(cfg.SecretFiles.Patterns())
+	for _, name := range sortedEnvironments(cfg) {
+		add(cfg.Environments[name].SecretFiles.Patterns())
+	}
+	return patterns
+}
+
+func sortedEnvironments(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Environments))
+	for name := range cfg.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gitAttributes формирует правила фильтра и diff-драйвера для открытых
+// файлов и их зашифрованных копий (ext). Шаблоны без "/" secret ищет
+// только в корне проекта, поэтому в .gitattributes они привязываются к корню.
+func gitAttributes(patterns []string, ext string) []string {
+	lines := make([]string, 0, 2*len(patterns)+1)
+	for _, p := range patterns {
+		p = rootedPattern(p)
+		lines = append(lines,
+			p+" filter="+gitFilter+" diff="+gitFilter+" merge="+gitFilter,
+			p+ext+" diff="+gitFilter+" merge="+gitFilter)
+	}
+	// .example-файлы остаются открытыми, даже если подходят под шаблон
+	return append(lines, "*.example.* !filter !diff !merge")
+}
+
+// writeManagedBlock заменяет блок между метками secret в файле path
+// или добавляет его в конец; остальные строки не меняются.
+// Пустой lines удаляет блок.
+func writeManagedBlock(path string, lines []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	block := managedBegin + "\n" + strings.Join(lines, "\n") + "\n" + managedEnd + "\n"
+
+	content := string(data)
+	begin := strings.Index(content, managedBegin)
+	end := strings.Index(content, managedEnd)
+	switch {
+	case len(lines) == 0 && begin >= 0 && end > begin:
+		end += len(managedEnd)
+		if end < len(content) && content[end] == '\n' {
+			end++
+		}
+		content = strings.TrimSuffix(content[:begin], "\n\n") + "\n" + content[end:]
+		if strings.TrimSpace(content) == "" {
+			content = ""
+		}
+	case len(lines) == 0:
+		return nil
+	case begin >= 0 && end > begin:
+		end += len(managedEnd)
+		if end < len(content) && content[end] == '\n' {
+			end++
+		}
+		content = content[:begin] + block + content[end:]
+	case content == "":
+		content = block
+	default:
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "\n" + block
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// gitIgnoredFiles возвращает файлы, исключенные правилами .gitignore
+func gitIgnoredFiles(files []string) []string {
+	if len(files) == 0 {
+		return nil
+	}
+	out, _ := gitOutput(append([]string{"check-ignore", "--"}, files...)...)
+	var ignored []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			ignored = append(ignored, line)
+		}
+	}
+	return ignored
+}
+
+// filterOutput возвращает stdout для данных фильтра, а сообщения бэкендов
+// перенаправляет в stderr, чтобы они не попали в содержимое файла
+func filterOutput() *os.File {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	return out
+}
+
+func gitOutput(args ...string) ([]byte, error) {
+	return exec.Command("git", args...).Output()
+}
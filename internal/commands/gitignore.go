@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// updateGitignore поддерживает в .gitignore блок secret с открытыми
+// secret_files и расшифрованными копиями файлов files и зашифрованных
+// файлов в git, чтобы git add . не добавил их в репозиторий.
+// Ошибки не прерывают команду, а выводятся предупреждением.
+func updateGitignore(ext string, files ...string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	lines := gitignoreLines(cfg, ext, files)
+
+	before, _ := os.ReadFile(".gitignore")
+	if len(lines) == 0 && !bytes.Contains(before, []byte(managedBegin)) {
+		return
+	}
+	if err := writeManagedBlock(".gitignore", lines); err != nil {
+		fmt.Printf("⚠️ Не удалось обновить .gitignore: %v\n", err)
+		return
+	}
+	if after, _ := os.ReadFile(".gitignore"); !bytes.Equal(before, after) {
+		fmt.Printf("📝 .gitignore обновлен: правил для открытых секретных файлов — %d\n", len(lines))
+	}
+}
+
+// gitignoreLines формирует правила блока. Под фильтром secret git открытые
+// secret_files хранятся в git как шифротекст, поэтому не исключаются.
+func gitignoreLines(cfg *config.Config, ext string, files []string) []string {
+	filtered := gitFilterInstalled()
+	seen := map[string]bool{}
+	var lines []string
+	add := func(line string) {
+		if !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+
+	var patterns, negations []string
+	if !filtered {
+		patterns = allSecretPatterns(cfg)
+		for _, p := range patterns {
+			// Шифротекст коммитится, даже если подходит под шаблон (.env*)
+			// или лежит в директории из secret_files. Файлы в исключенной
+			// директории git вернуть не может, поэтому исключается ее содержимое.
+			if dir := strings.TrimSuffix(p, "/"); strings.HasSuffix(p, "/") || isDir(p) {
+				dir = rootedPattern(dir)
+				add(dir + "/**")
+				negations = append(negations, "!"+dir+"/**/", "!"+dir+"/**/*"+ext)
+				continue
+			}
+			add(rootedPattern(p))
+			if ok, _ := filepath.Match(p, p+ext); ok {
+				negations = append(negations, "!"+rootedPattern(p)+ext)
+			}
+		}
+	}
+
+	// Расшифрованные копии, в том числе файлов вне secret_files
+	if out, err := gitOutput("ls-files", "-z", "--", "*"+ext); err == nil {
+		for _, tracked := range strings.Split(string(out), "\x00") {
+			if tracked != "" {
+				files = append(files, strings.TrimSuffix(tracked, ext))
+			}
+		}
+	}
+	for _, file := range files {
+		file = filepath.ToSlash(filepath.Clean(file))
+		if (filtered && matchesSecretFiles(cfg, file)) || matchesPattern(patterns, file) {
+			continue
+		}
+		add("/" + file)
+	}
+	if len(lines) > 0 {
+		for _, n := range negations {
+			add(n)
+		}
+		// .example-файлы коммитятся, даже если подходят под шаблон
+		add("!*.example.*")
+	}
+	return lines
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// trackedPlaintext возвращает файлы из secret_files, которые git хранит
+// открытым текстом (без фильтра secret git)
+func trackedPlaintext(cfg *config.Config) []string {
+	out, err := gitOutput("ls-files", "-z")
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path == "" || strings.Contains(filepath.Base(path), ".example.") || !matchesSecretFiles(cfg, path) {
+			continue
+		}
+		blob, err := gitOutput("cat-file", "blob", ":"+path)
+		if err == nil && len(blob) > 0 && !backends.LooksEncrypted(blob) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// checkTrackedPlaintext предупреждает об открытых секретах в git
+func checkTrackedPlaintext(cfg *config.Config) {
+	files := trackedPlaintext(cfg)
+	if len(files) == 0 {
+		return
+	}
+	fmt.Printf("\n⚠️ Открытые секретные файлы в git (%d):\n", len(files))
+	for _, f := range files {
+		fmt.Printf("  - %s\n", f)
+	}
+	fmt.Println("Уберите их из индекса: git rm --cached <файл> (история git их сохранит — смените значения)")
+}
+
+func gitFilterInstalled() bool {
+	_, err := gitOutput("config", "--get", "filter."+gitFilter+".clean")
+	return err == nil
+}
+
+// rootedPattern привязывает шаблон без "/" к корню проекта:
+// secret ищет такие файлы только в корне
+func rootedPattern(p string) string {
+	if !strings.Contains(p, "/") {
+		return "/" + p
+	}
+	return p
+}
+
+// matchesPattern сообщает, покрыт ли путь одним из шаблонов .gitignore
+// или лежит в директории из них
+func matchesPattern(patterns []string, file string) bool {
+	for _, p := range patterns {
+		p = strings.Trim(p, "/")
+		if ok, _ := filepath.Match(p, file); ok || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
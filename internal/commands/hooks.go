@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/internal/fields"
+	"github.com/Avdushin/secret/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// @ hooks cmd
+func HooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Git-хуки, защищающие от коммита открытых секретов",
+		Long: `Устанавливает pre-commit хук, который не дает закоммитить открытые
+secret_files, устаревший шифротекст и .example, не совпадающий с ключами
+зашифрованного файла.
+  secret hooks install`,
+	}
+
+	cmd.AddCommand(hooksInstallCmd())
+	cmd.AddCommand(hooksPreCommitCmd())
+	return cmd
+}
+
+func hooksInstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install",
+		Short: "Устанавливает pre-commit хук",
+		Long: `Добавляет вызов secret hooks pre-commit в хук pre-commit репозитория
+(с учетом core.hooksPath). Существующий хук сохраняется, блок secret
+добавляется в его конец.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := config.LoadConfig(); err != nil {
+				fmt.Printf("ошибка загрузки конфига: %v\n", err)
+				os.Exit(1)
+			}
+			out, err := gitOutput("rev-parse", "--git-path", "hooks")
+			if err != nil {
+				fmt.Println("❌ Текущая директория не является git-репозиторием")
+				os.Exit(1)
+			}
+			self, err := os.Executable()
+			if err != nil {
+				fmt.Printf("❌ Не удалось определить путь к secret: %v\n", err)
+				os.Exit(1)
+			}
+
+			dir := strings.TrimSpace(string(out))
+			hook := filepath.Join(dir, "pre-commit")
+			if err := installHook(hook, fmt.Sprintf("%q hooks pre-commit || exit 1", self)); err != nil {
+				fmt.Printf("❌ Ошибка записи хука %s: %v\n", hook, err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Хук %s установлен\n", hook)
+			fmt.Println("Пропустить проверку: git commit --no-verify")
+		},
+	}
+}
+
+func hooksPreCommitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pre-commit",
+		Short: "Проверяет индекс перед коммитом",
+		Long: `Вызывается хуком pre-commit. Коммит отклоняется, если в индексе:
+  - открытый файл из secret_files (без фильтра secret git);
+  - шифротекст, открытый файл которого изменен позже;
+  - зашифрованный файл, ключи которого не совпадают с .example.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			problems, err := preCommitCheck()
+			if err != nil {
+				fmt.Printf("❌ secret pre-commit: %v\n", err)
+				os.Exit(1)
+			}
+			if len(problems) == 0 {
+				return
+			}
+			fmt.Println("❌ secret: коммит отклонен")
+			for _, p := range problems {
+				fmt.Printf("  - %s\n", p)
+			}
+			fmt.Println("Пропустить проверку: git commit --no-verify")
+			os.Exit(1)
+		},
+	}
+}
+
+// installHook добавляет блок secret в скрипт хука, создавая его при необходимости
+func installHook(hook, line string) error {
+	if err := os.MkdirAll(filepath.Dir(hook), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(hook); os.IsNotExist(err) {
+		if err := os.WriteFile(hook, []byte("#!/bin/sh\n"), 0755); err != nil {
+			return err
+		}
+	}
+	if err := writeManagedBlock(hook, []string{line}); err != nil {
+		return err
+	}
+	return os.Chmod(hook, 0755)
+}
+
+// preCommitCheck проверяет файлы в индексе и возвращает найденные проблемы
+func preCommitCheck() ([]string, error) {
+	cfg, backend, err := loadBackend()
+	if err != nil {
+		return nil, err
+	}
+	out, err := gitOutput("diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить список файлов индекса: %v", err)
+	}
+
+	var problems []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path == "" || strings.Contains(filepath.Base(path), ".example.") {
+			continue
+		}
+		file := plainName(backend, path)
+		if !matchesSecretFiles(cfg, file) {
+			continue
+		}
+		blob, err := gitOutput("cat-file", "blob", ":"+path)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать %s из индекса: %v", path, err)
+		}
+
+		switch {
+		case file == path && !backends.LooksEncrypted(blob):
+			problems = append(problems, fmt.Sprintf("%s: открытый секрет в индексе. Выполните: git rm --cached %s && secret encrypt %s (или secret git install)", path, path, path))
+			continue
+		case file != path && newerThan(file, path):
+			_, fileBackend, err := loadFileBackend(file)
+			if err != nil {
+				return nil, err
+			}
+			current, _ := os.ReadFile(file)
+			if plaintext, err := decryptBlob(fileBackend, file, blob); err != nil || !bytes.Equal(plaintext, current) {
+				problems = append(problems, fmt.Sprintf("%s устарел: %s изменен позже. Выполните: secret encrypt %s", path, file, file))
+				continue
+			}
+		}
+
+		if problem := checkExample(file, blob); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems, nil
+}
+
+// matchesSecretFiles сообщает, подходит ли файл под secret_files
+// основного конфига или одного из окружений
+func matchesSecretFiles(cfg *config.Config, file string) bool {
+	if cfg.SecretFiles.Find(file) != nil {
+		return true
+	}
+	for _, env := range cfg.Environments {
+		if env.SecretFiles.Find(file) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newerThan сообщает, изменен ли файл a позже файла b
+func newerThan(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return ai.ModTime().After(bi.ModTime())
+}
+
+// checkExample сравнивает ключи зашифрованного файла с его .example
+// (версией из индекса, если она есть). Файлы без .example, неизвестных
+// форматов или недоступные для расшифровки не проверяются.
+func checkExample(file string, ciphertext []byte) string {
+	format := fields.DetectFormat(file)
+	if format == fields.Unknown {
+		return ""
+	}
+	example := backends.ExampleName(file)
+	exampleContent, err := gitOutput("cat-file", "blob", ":"+filepath.ToSlash(example))
+	if err != nil {
+		if exampleContent, err = os.ReadFile(example); err != nil {
+			return ""
+		}
+	}
+
+	_, backend, err := loadFileBackend(file)
+	if err != nil {
+		return ""
+	}
+	plaintext, err := decryptBlob(backend, file, ciphertext)
+	if err != nil {
+		fmt.Printf("⚠️ %s: нет доступа, .example не проверен\n", file)
+		return ""
+	}
+
+	secretKeys, err := scanKeys(format, plaintext)
+	if err != nil {
+		return ""
+	}
+	exampleKeys, err := scanKeys(format, exampleContent)
+	if err != nil {
+		return fmt.Sprintf("%s: %v", example, err)
+	}
+
+	var missing, extra []string
+	for key := range secretKeys {
+		if !exampleKeys[key] {
+			missing = append(missing, key)
+		}
+	}
+	for key := range exampleKeys {
+		if !secretKeys[key] {
+			extra = append(extra, key)
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		return ""
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+
+	var diff []string
+	if len(missing) > 0 {
+		diff = append(diff, "нет ключей "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		diff = append(diff, "лишние ключи "+strings.Join(extra, ", "))
+	}
+	return fmt.Sprintf("%s не совпадает с %s: %s. Выполните: secret encrypt %s", example, file, strings.Join(diff, "; "), file)
+}
+
+func scanKeys(format fields.Format, content []byte) (map[string]bool, error) {
+	values, err := fields.Scan(format, content)
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	for _, v := range values {
+		keys[v.Key] = true
+	}
+	return keys, nil
+}
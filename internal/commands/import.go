@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -11,15 +10,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
-// @ ImportKeyCmd импортирует GPG-ключи проекта
+// @ ImportKeyCmd импортирует ключи проекта
 func ImportKeyCmd() *cobra.Command {
 	var keyDir string
 	var force bool
 
 	cmd := &cobra.Command{
 		Use:   "import [directory]",
-		Short: "Импортирует GPG-ключи проекта",
-		Long: `Импортирует GPG-ключи проекта из указанной директории или автоматически
+		Short: "Импортирует ключи проекта",
+		Long: `Импортирует ключи проекта из указанной директории или автоматически
 ищет ключи в текущей директории и поддиректориях.
 Примеры:
   secret import # Автопоиск в текущей директории
@@ -33,17 +32,14 @@ func ImportKeyCmd() *cobra.Command {
 			}
 
 			// Загружаем конфиг для получения имени проекта
-			cfg, err := config.LoadConfig()
+			cfg, backend, err := loadBackend()
 			if err != nil {
-				fmt.Printf("Ошибка загрузки конфига: %v\n", err)
+				fmt.Println(err)
 				os.Exit(1)
 			}
 
 			// Определяем префикс для поиска файлов ключей
-			filenamePrefix := "key"
-			if cfg.ProjectName != "" {
-				filenamePrefix = strings.ToLower(strings.ReplaceAll(cfg.ProjectName, " ", "_"))
-			}
+			filenamePrefix := keyFilePrefix(cfg)
 
 			// Поиск файлов ключей
 			pubKeyPath, privKeyPath, err := findKeyFiles(keyDir, filenamePrefix)
@@ -59,20 +55,20 @@ func ImportKeyCmd() *cobra.Command {
 
 			// Импортируем публичный ключ
 			fmt.Println("\n📥 Импортируем публичный ключ...")
-			if err := importKey(pubKeyPath); err != nil {
+			if err := backend.ImportKey(pubKeyPath); err != nil {
 				fmt.Printf("❌ Ошибка импорта публичного ключа: %v\n", err)
 				os.Exit(1)
 			}
 
 			// Импортируем приватный ключ
 			fmt.Println("📥 Импортируем приватный ключ...")
-			if err := importKey(privKeyPath); err != nil {
+			if err := backend.ImportKey(privKeyPath); err != nil {
 				fmt.Printf("❌ Ошибка импорта приватного ключа: %v\n", err)
 				os.Exit(1)
 			}
 
 			// После импорта определяем keyID и сохраняем в конфиг
-			keyID, err := detectProjectKey(cfg.ProjectName)
+			keyID, err := backend.FindKey(cfg.ProjectName)
 			if err != nil {
 				fmt.Printf("❌ Ошибка определения keyID после импорта: %v\n", err)
 				os.Exit(1)
@@ -151,51 +147,3 @@ func findKeyFiles(searchDir, prefix string) (string, string, error) {
 
 	return pubKeyPath, privKeyPath, nil
 }
-
-// importKey импортирует ключ с помощью GPG
-func importKey(keyPath string) error {
-	cmd := exec.Command("gpg", "--import", keyPath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ошибка выполнения gpg --import: %v", err)
-	}
-
-	return nil
-}
-
-// detectProjectKey определяет keyID по имени проекта
-func detectProjectKey(projectName string) (string, error) {
-	out, err := exec.Command("gpg", "--list-secret-keys", "--keyid-format=LONG").CombinedOutput()
-	if err != nil {
-		return "", err
-	}
-
-	lines := strings.Split(string(out), "\n")
-	for idx, line := range lines {
-		if strings.Contains(line, "uid") && strings.Contains(line, projectName) {
-			// Ищем "sec" в предыдущих строках (назад до 5 строк)
-			for j := 1; j <= 5; j++ {
-				if idx-j < 0 {
-					break
-				}
-				prevLine := lines[idx-j]
-				if strings.Contains(prevLine, "sec") {
-					parts := strings.Fields(prevLine)
-					if len(parts) >= 2 {
-						keyPart := parts[1]
-						if strings.Contains(keyPart, "/") {
-							keyParts := strings.Split(keyPart, "/")
-							if len(keyParts) == 2 {
-								return keyParts[1], nil
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-	return "", fmt.Errorf("не удалось найти ключ для проекта %s после импорта", projectName)
-}
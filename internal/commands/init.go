@@ -4,12 +4,12 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Avdushin/secret/internal/backends"
 	"github.com/Avdushin/secret/pkg/config"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -23,6 +23,15 @@ func InitCmd() *cobra.Command {
 		Use:   "init",
 		Short: "Инициализирует проект для работы с секретами",
 		Run: func(cmd *cobra.Command, args []string) {
+			cfg := &config.Config{Backend: backend}
+
+			// Проверяем бэкенд до того, как задавать вопросы
+			b, err := backends.New(cfg)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+
 			fmt.Printf("Инициализация с бэкендом: %s\n", backend)
 
 			//@ имя текущей папки как имя проекта по умолчанию
@@ -39,92 +48,272 @@ func InitCmd() *cobra.Command {
 				defaultProjectName,
 			)
 
-			//@ Запрашиваем файлы/директории для шифрования
-			fmt.Println("\nУкажите файлы или директории для шифрования (через запятую)")
-			fmt.Printf("По умолчанию: %s\n", strings.Join(config.DefaultSecretFiles, ", "))
-			filesInput := promptUser("Файлы/директории: ", "")
+			//@ Находим файлы с секретами и предлагаем выбрать
+			secretFiles := promptSecretFiles(b.Ext())
 
-			var secretFiles []string
-			if filesInput == "" {
-				secretFiles = config.DefaultSecretFiles
-			} else {
-				secretFiles = strings.Split(filesInput, ",")
-				for i := range secretFiles {
-					secretFiles[i] = strings.TrimSpace(secretFiles[i])
+			switch backend {
+			case "age":
+				//@ Для age вместо генерации ключа проекта собираем получателей
+				recipients, err := promptAgeRecipients(b, projectName)
+				if err != nil {
+					fmt.Printf("Ошибка настройки получателей: %v\n", err)
+					os.Exit(1)
 				}
-			}
-
-			//@ Запрашиваем параметры GPG ключа
-			fmt.Println("\n⚙️  Настройка GPG ключа")
-
-			// Выбор типа ключа
-			keyType := promptUserWithOptions(
-				"Тип ключа (RSA/DSA/ECC) [RSA]: ",
-				[]string{"RSA", "DSA", "ECC"},
-				"RSA",
-			)
-
-			// Длина ключа
-			var keyLength int
-			switch keyType {
-			case "RSA":
-				keyLength = promptInt("Длина RSA ключа (2048/3072/4096) [4096]: ", 4096, []int{2048, 3072, 4096})
-			case "DSA":
-				keyLength = promptInt("Длина DSA ключа (1024/2048/3072) [2048]: ", 2048, []int{1024, 2048, 3072})
-			case "ECC":
-				keyLength = 0 // ECC использует кривые, а не длину
-			}
-
-			// Срок действия
-			expireDate := promptUser("Срок действия ключа (0=бессрочно, 1y, 12m, 52w, 355) [2y]: ", "2y")
-
-			// Парольная фраза
-			usePassphrase := promptYesNo("Использовать парольную фразу для ключа? (y/N): ", false)
-			var passphrase string
-			if usePassphrase {
-				passphrase = promptPassword("Введите парольную фразу: ")
-				confirm := promptPassword("Подтвердите парольную фразу: ")
-				if passphrase != confirm {
-					fmt.Println("❌ Парольные фразы не совпадают!")
+				cfg.Recipients = recipients
+			case "vault":
+				//@ Для Vault ключи не нужны, только параметры подключения
+				cfg.Vault = promptVaultConfig()
+			case "bitwarden":
+				//@ Для Bitwarden указываем, куда складывать заметки
+				cfg.Bitwarden = promptBitwardenConfig()
+			default:
+				//@ Запрашиваем параметры GPG ключа
+				params := promptKeyParams()
+
+				//@ Создаем GPG ключ
+				keyID, err := generateProjectKey(b, projectName, params)
+				if err != nil {
+					fmt.Printf("Ошибка создания ключа: %v\n", err)
 					os.Exit(1)
 				}
-			}
-
-			//@ Создаем GPG ключ
-			timestamp := time.Now().Format("2006-01-02")
-			keyName := fmt.Sprintf("%s Project Key (%s)", projectName, timestamp)
-			keyEmail := fmt.Sprintf("project+%s@team.org", strings.ToLower(projectName))
+				cfg.GPGKey = keyID
+				cfg.Key = &config.KeySpec{Type: params.Type, Length: params.Length, Expire: params.Expire}
 
-			fmt.Printf("\nСоздаем GPG-ключ для проекта: %s\n", keyName)
-			keyID, err := generateGPGKey(keyName, keyEmail, "Auto-generated by secret tool", keyType, keyLength, expireDate, passphrase)
-			if err != nil {
-				fmt.Printf("Ошибка создания ключа: %v\n", err)
-				os.Exit(1)
+				// Ключ создателя проекта становится первым получателем;
+				// остальные участники добавляют свои публичные ключи
+				if info, err := b.KeyInfo(keyID); err == nil {
+					cfg.Recipients = []string{info.Fingerprint}
+				}
 			}
 
 			//@ Сохраняем конфиг
-			cfg := &config.Config{
-				Backend:     backend,
-				GPGKey:      keyID,
-				ProjectName: projectName,
-				SecretFiles: secretFiles,
-			}
+			cfg.ProjectName = projectName
+			cfg.SecretFiles = config.NewSecretFiles(secretFiles)
 
 			if err := config.SaveConfig(cfg); err != nil {
 				fmt.Printf("Ошибка сохранения конфига: %v\n", err)
 				os.Exit(1)
 			}
+			updateGitignore(b.Ext())
 
-			fmt.Printf("\n✅ Успешно! Ключ создан (ID: %s)\n", keyID)
-			fmt.Printf("🔑 Для экспорта ключа выполните: secret export-key\n")
+			if cfg.GPGKey != "" {
+				fmt.Printf("\n✅ Успешно! Ключ создан (ID: %s)\n", cfg.GPGKey)
+				fmt.Printf("🔑 Для экспорта ключа выполните: secret export-key\n")
+			} else {
+				fmt.Printf("\n✅ Успешно! Получателей: %d\n", len(cfg.Recipients))
+			}
 			fmt.Printf("🔒 Для шифрования файлов выполните: secret encrypt\n")
 		},
 	}
 
-	cmd.Flags().StringVarP(&backend, "backend", "b", "gpg", "Бэкенд (gpg, vault, bitwarden)")
+	cmd.Flags().StringVarP(&backend, "backend", "b", config.DefaultBackend, fmt.Sprintf("Бэкенд (%s)", strings.Join(backends.Names(), ", ")))
 	return cmd
 }
 
+// promptSecretFiles предлагает найденные в проекте файлы списком с отметками.
+// Если ничего не найдено, спрашивает имена файлов через запятую.
+func promptSecretFiles(ext string) []string {
+	candidates := findSecretFiles(ext)
+	if len(candidates) == 0 {
+		fmt.Println("\nУкажите файлы или директории для шифрования (через запятую)")
+		fmt.Printf("По умолчанию: %s\n", strings.Join(config.DefaultSecretFiles, ", "))
+		filesInput := promptUser("Файлы/директории: ", "")
+		if filesInput == "" {
+			return config.DefaultSecretFiles
+		}
+		files := strings.Split(filesInput, ",")
+		for i := range files {
+			files[i] = strings.TrimSpace(files[i])
+		}
+		return files
+	}
+
+	fmt.Println("\nНайдены файлы, похожие на секреты. Отмеченные [x] будут шифроваться.")
+	fmt.Println("Номера через запятую переключают отметку, имена файлов добавляют их в список.")
+	for {
+		for i, c := range candidates {
+			mark := " "
+			if c.checked {
+				mark = "x"
+			}
+			note := ""
+			if c.note != "" {
+				note = " — " + c.note
+			}
+			fmt.Printf("  [%s] %d. %s%s\n", mark, i+1, c.path, note)
+		}
+		input := promptUser("Номера или файлы (Enter — продолжить): ", "")
+		if input == "" {
+			break
+		}
+		for _, item := range strings.Split(input, ",") {
+			item = strings.TrimSpace(item)
+			if n, err := strconv.Atoi(item); err == nil && n >= 1 && n <= len(candidates) {
+				candidates[n-1].checked = !candidates[n-1].checked
+			} else if item != "" {
+				candidates = append(candidates, fileCandidate{path: item, checked: true})
+			}
+		}
+		fmt.Println()
+	}
+
+	var files []string
+	for _, c := range candidates {
+		if c.checked {
+			files = append(files, c.path)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf("ℹ️ Файлы не выбраны, используются: %s\n", strings.Join(config.DefaultSecretFiles, ", "))
+		return config.DefaultSecretFiles
+	}
+	return files
+}
+
+// promptKeyParams запрашивает тип, длину, срок действия и парольную фразу ключа
+func promptKeyParams() backends.KeyParams {
+	fmt.Println("\n⚙️  Настройка GPG ключа")
+
+	// Выбор типа ключа
+	keyType := promptUserWithOptions(
+		"Тип ключа (RSA/DSA/ECC) [RSA]: ",
+		[]string{"RSA", "DSA", "ECC"},
+		"RSA",
+	)
+
+	// Длина ключа
+	var keyLength int
+	switch keyType {
+	case "RSA":
+		keyLength = promptInt("Длина RSA ключа (2048/3072/4096) [4096]: ", 4096, []int{2048, 3072, 4096})
+	case "DSA":
+		keyLength = promptInt("Длина DSA ключа (1024/2048/3072) [2048]: ", 2048, []int{1024, 2048, 3072})
+	case "ECC":
+		keyLength = 0 // ECC использует кривые, а не длину
+	}
+
+	// Срок действия
+	expireDate := promptUser("Срок действия ключа (0=бессрочно, 1y, 12m, 52w, 355) [2y]: ", "2y")
+
+	return backends.KeyParams{
+		Type:       keyType,
+		Length:     keyLength,
+		Expire:     expireDate,
+		Passphrase: promptKeyPassphrase(),
+	}
+}
+
+// promptKeyPassphrase спрашивает, защищать ли ключ парольной фразой
+func promptKeyPassphrase() string {
+	usePassphrase := promptYesNo("Использовать парольную фразу для ключа? (y/N): ", false)
+	var passphrase string
+	if usePassphrase {
+		passphrase = promptPassword("Введите парольную фразу: ")
+		confirm := promptPassword("Подтвердите парольную фразу: ")
+		if passphrase != confirm {
+			fmt.Println("❌ Парольные фразы не совпадают!")
+			os.Exit(1)
+		}
+	}
+	return passphrase
+}
+
+// generateProjectKey создает ключ проекта с именем и email по шаблону secret.
+// Скобки в имени не используются: openpgp не допускает их в user id.
+func generateProjectKey(b backends.Backend, projectName string, params backends.KeyParams) (string, error) {
+	timestamp := time.Now().Format("2006-01-02")
+	params.Name = fmt.Sprintf("%s Project Key %s", projectName, timestamp)
+	params.Email = fmt.Sprintf("project+%s@team.org", strings.ToLower(projectName))
+	params.Comment = "Auto-generated by secret tool"
+
+	fmt.Printf("\nСоздаем GPG-ключ для проекта: %s\n", params.Name)
+	return b.GenerateKey(params)
+}
+
+// promptVaultConfig запрашивает параметры подключения к Vault.
+// Токены и secret_id не запрашиваются: они передаются через окружение.
+func promptVaultConfig() *config.VaultConfig {
+	fmt.Println("\n⚙️  Настройка HashiCorp Vault (KV v2)")
+	vcfg := &config.VaultConfig{}
+	vcfg.Address = promptUser(fmt.Sprintf("Адрес Vault [%s]: ", os.Getenv("VAULT_ADDR")), os.Getenv("VAULT_ADDR"))
+	vcfg.Mount = promptUser("Точка монтирования KV v2 [secret]: ", "secret")
+	vcfg.Auth = promptUserWithOptions("Аутентификация (token/approle) [token]: ", []string{"token", "approle"}, "token")
+	if vcfg.Auth == "approle" {
+		vcfg.RoleID = promptUser("AppRole role_id (пусто — из VAULT_ROLE_ID): ", "")
+		fmt.Println("ℹ️ secret_id передается через переменную окружения VAULT_SECRET_ID")
+	} else {
+		fmt.Println("ℹ️ Токен берется из VAULT_TOKEN или ~/.vault-token")
+	}
+	return vcfg
+}
+
+// promptBitwardenConfig запрашивает организацию и коллекцию для заметок
+func promptBitwardenConfig() *config.BitwardenConfig {
+	fmt.Println("\n⚙️  Настройка Bitwarden")
+	fmt.Println("ℹ️ ID организации и коллекции можно узнать через: bw list organizations / bw list collections")
+	bw := &config.BitwardenConfig{}
+	bw.OrganizationID = promptUser("ID организации (пусто — личное хранилище): ", "")
+	if bw.OrganizationID != "" {
+		bw.CollectionID = promptUser("ID коллекции: ", "")
+	} else {
+		bw.FolderID = promptUser("ID папки (необязательно): ", "")
+	}
+	return bw
+}
+
+// promptAgeRecipients запрашивает публичные ключи age/SSH получателей.
+// По умолчанию используется ~/.ssh/id_ed25519.pub, а если его нет —
+// создается новая идентичность age.
+func promptAgeRecipients(b backends.Backend, projectName string) ([]string, error) {
+	var defaultRecipient string
+	if home, err := os.UserHomeDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".ssh", "id_ed25519.pub")); err == nil {
+			defaultRecipient = strings.TrimSpace(string(data))
+		}
+	}
+
+	fmt.Println("\n⚙️  Настройка получателей age")
+	fmt.Println("Укажите публичные ключи (age1... или ssh-ed25519 ...) или пути к .pub файлам через запятую")
+	if defaultRecipient != "" {
+		fmt.Printf("По умолчанию: %s\n", defaultRecipient)
+	} else {
+		fmt.Println("По умолчанию: будет создан новый ключ age")
+	}
+	input := promptUser("Получатели: ", "")
+
+	var recipients []string
+	for _, item := range strings.Split(input, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		// Путь к файлу с ключами: по одному получателю на строку
+		if data, err := os.ReadFile(item); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if line != "" && !strings.HasPrefix(line, "#") {
+					recipients = append(recipients, line)
+				}
+			}
+			continue
+		}
+		recipients = append(recipients, item)
+	}
+
+	if len(recipients) == 0 && defaultRecipient != "" {
+		recipients = []string{defaultRecipient}
+	}
+	if len(recipients) == 0 {
+		recipient, err := b.GenerateKey(backends.KeyParams{Name: projectName + " Project Key"})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("🔑 Создан ключ age: %s\n", recipient)
+		recipients = []string{recipient}
+	}
+	return recipients, nil
+}
+
 func promptUser(prompt, defaultValue string) string {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Print(prompt)
@@ -237,75 +426,3 @@ func promptPassword(prompt string) string {
 	input, _ := reader.ReadString('\n')
 	return strings.TrimSpace(input)
 }
-
-func generateGPGKey(name, email, comment, keyType string, keyLength int, expireDate, passphrase string) (string, error) {
-	// Создаем batch файл с выбранными параметрами
-	batchContent := fmt.Sprintf(`Key-Type: %s
-`, keyType)
-
-	if keyLength > 0 {
-		batchContent += fmt.Sprintf("Key-Length: %d\n", keyLength)
-	}
-
-	if keyType == "RSA" {
-		batchContent += `Subkey-Type: RSA
-Subkey-Length: ` + fmt.Sprintf("%d", keyLength) + `
-`
-	}
-
-	batchContent += fmt.Sprintf(`Name-Real: %s
-Name-Email: %s
-Name-Comment: %s
-Expire-Date: %s
-`, name, email, comment, expireDate)
-
-	if passphrase != "" {
-		batchContent += fmt.Sprintf("Passphrase: %s\n", passphrase)
-	} else {
-		batchContent += "%no-protection\n"
-	}
-
-	batchContent += "%commit\n"
-
-	// Создаем временный файл
-	tmpFile, err := os.CreateTemp("", "gpg-batch-*.txt")
-	if err != nil {
-		return "", fmt.Errorf("не удалось создать временный файл: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-
-	if _, err := tmpFile.WriteString(batchContent); err != nil {
-		return "", fmt.Errorf("не удалось записать во временный файл: %v", err)
-	}
-	tmpFile.Close()
-
-	// Выполняем команду создания ключа
-	cmd := exec.Command("gpg", "--batch", "--gen-key", tmpFile.Name())
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("%s: %v", string(output), err)
-	}
-
-	// Ищем ID созданного ключа
-	cmd = exec.Command("gpg", "--list-secret-keys", "--keyid-format", "LONG", email)
-	output, err = cmd.CombinedOutput()
-	if err != nil {
-		return "", err
-	}
-
-	// Парсим ID ключа
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "sec") {
-			parts := strings.Fields(line)
-			if len(parts) > 2 {
-				keyParts := strings.Split(parts[1], "/")
-				if len(keyParts) > 1 {
-					return keyParts[1], nil
-				}
-			}
-		}
-	}
-
-	return "", fmt.Errorf("не удалось определить ID созданного ключа")
-}
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+func TestGenerateProjectKeyOpenPGP(t *testing.T) {
+	t.Setenv("SECRET_OPENPGP_HOME", t.TempDir())
+	b, err := backends.NewOpenPGPBackend(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, err := generateProjectKey(b, "My-App", backends.KeyParams{Type: "ECC", Expire: "0"})
+	if err != nil {
+		t.Fatalf("generateProjectKey: %v", err)
+	}
+	info, err := b.KeyInfo(keyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(info.Name, "My-App Project Key ") || info.Email != "project+my-app@team.org" {
+		t.Errorf("key info = %+v", info)
+	}
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// requestsDir — директория запросов доступа, которые коммитятся в репозиторий
+var requestsDir = filepath.Join(".secret", "requests")
+
+// accessRequest — запрос нового участника на доступ к секретам проекта.
+// Подпись подтверждает, что автор запроса владеет приватной частью ключа.
+type accessRequest struct {
+	Name        string `yaml:"name"`
+	Email       string `yaml:"email"`
+	Fingerprint string `yaml:"fingerprint"`
+	Created     string `yaml:"created"`
+	PublicKey   string `yaml:"public_key"`
+	Signature   string `yaml:"signature"`
+}
+
+// payload возвращает подписываемую часть запроса
+func (r *accessRequest) payload() []byte {
+	return []byte(strings.Join([]string{r.Name, r.Email, r.Fingerprint, r.Created, r.PublicKey}, "\n"))
+}
+
+// @ join cmd
+func JoinCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "join",
+		Short: "Запрашивает доступ к секретам проекта",
+		Long: `Создает личный ключ нового участника и записывает подписанный запрос
+доступа (публичный ключ, имя и email) в .secret/requests/.
+Закоммитьте запрос и попросите участника с доступом выполнить:
+  secret members approve`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			// Бэкенд должен уметь подписывать запросы
+			if _, err := backend.Verify(nil, nil, nil); errors.Is(err, backends.ErrNotSupported) {
+				fmt.Printf("❌ Бэкенд %s не поддерживает подписанные запросы доступа\n", backend.Name())
+				if backend.Name() == "age" {
+					fmt.Println("Передайте участнику проекта свой публичный ключ для: secret members add")
+				}
+				os.Exit(1)
+			}
+
+			fmt.Printf("👋 Запрос доступа к проекту %s\n", cfg.ProjectName)
+			defaultName, defaultEmail := gitConfigValue("user.name"), gitConfigValue("user.email")
+			name := promptUser(fmt.Sprintf("Ваше имя [%s]: ", defaultName), defaultName)
+			email := promptUser(fmt.Sprintf("Ваш email [%s]: ", defaultEmail), defaultEmail)
+			if name == "" || email == "" {
+				fmt.Println("❌ Имя и email обязательны")
+				os.Exit(1)
+			}
+
+			//@ Используем существующий личный ключ или создаем новый
+			keyID, err := backend.FindKey(email)
+			if err != nil || !promptYesNo(fmt.Sprintf("Найден ключ %s. Использовать его? (Y/n): ", keyID), true) {
+				params := promptKeyParams()
+				params.Name = name
+				params.Email = email
+				params.Comment = cfg.ProjectName
+				fmt.Printf("\nСоздаем личный ключ: %s <%s>\n", name, email)
+				if keyID, err = backend.GenerateKey(params); err != nil {
+					fmt.Printf("❌ Ошибка создания ключа: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			req, err := newAccessRequest(backend, keyID, name, email)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+
+			path, err := writeAccessRequest(req)
+			if err != nil {
+				fmt.Printf("❌ Ошибка сохранения запроса: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("\n✅ Запрос доступа сохранен в %s\n", path)
+			fmt.Printf("🔑 Отпечаток ключа: %s\n", req.Fingerprint)
+			fmt.Println("Закоммитьте файл и сообщите отпечаток участнику с доступом,")
+			fmt.Println("чтобы он выполнил: secret members approve")
+		},
+	}
+	return cmd
+}
+
+// newAccessRequest формирует и подписывает запрос доступа для ключа keyID
+func newAccessRequest(backend backends.Backend, keyID, name, email string) (*accessRequest, error) {
+	info, err := backend.KeyInfo(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("ключ %s не найден: %v", keyID, err)
+	}
+	pub, err := backend.ExportKey(keyID, false)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка экспорта публичного ключа: %v", err)
+	}
+
+	req := &accessRequest{
+		Name:        name,
+		Email:       email,
+		Fingerprint: info.Fingerprint,
+		Created:     time.Now().UTC().Format(time.RFC3339),
+		PublicKey:   string(pub),
+	}
+	sig, err := backend.Sign(keyID, req.payload())
+	if err != nil {
+		return nil, err
+	}
+	req.Signature = string(sig)
+	return req, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func writeAccessRequest(req *accessRequest) (string, error) {
+	if err := os.MkdirAll(requestsDir, 0755); err != nil {
+		return "", err
+	}
+	data, err := yaml.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	name := unsafeFileChars.ReplaceAllString(strings.ToLower(req.Email), "_")
+	path := filepath.Join(requestsDir, name+".yaml")
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// readAccessRequest читает запрос и проверяет, что он подписан своим же ключом
+func readAccessRequest(backend backends.Backend, path string) (*accessRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var req accessRequest
+	if err := yaml.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("неверный формат запроса: %v", err)
+	}
+	if req.PublicKey == "" || req.Signature == "" {
+		return nil, fmt.Errorf("в запросе нет публичного ключа или подписи")
+	}
+
+	signer, err := backend.Verify(req.payload(), []byte(req.Signature), []byte(req.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(signer, req.Fingerprint) {
+		return nil, fmt.Errorf("запрос подписан ключом %s, а не %s", signer, req.Fingerprint)
+	}
+	return &req, nil
+}
+
+// gitConfigValue возвращает значение из git config или пустую строку
+func gitConfigValue(key string) string {
+	out, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
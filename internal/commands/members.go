@@ -0,0 +1,560 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// @ members cmd
+func MembersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "members",
+		Short: "Управляет участниками проекта (получателями шифрования)",
+		Long: `Управляет списком recipients в .secret/config.yaml.
+Каждый участник расшифровывает файлы своим личным ключом, поэтому
+приватные ключи передавать не нужно. После изменения списка все
+файлы из secret_files (и окружений без своих recipients) перешифровываются
+для нового набора получателей.`,
+	}
+
+	cmd.AddCommand(membersListCmd())
+	cmd.AddCommand(membersAddCmd())
+	cmd.AddCommand(membersRemoveCmd())
+	cmd.AddCommand(membersApproveCmd())
+	return cmd
+}
+
+func membersListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Показывает получателей проекта",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			recipients := cfg.EncryptionRecipients()
+			if len(recipients) == 0 {
+				fmt.Println("ℹ️ Получатели не настроены")
+				return
+			}
+			fmt.Printf("👥 Получатели (%d):\n", len(recipients))
+			for _, r := range recipients {
+				fmt.Printf("  %s\n", describeMember(backend, r))
+			}
+
+			names := make([]string, 0, len(cfg.Groups))
+			for name := range cfg.Groups {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("\n👥 Группа %s (%d):\n", name, len(cfg.Groups[name]))
+				for _, r := range cfg.Groups[name] {
+					fmt.Printf("  %s\n", describeMember(backend, r))
+				}
+			}
+		},
+	}
+}
+
+func membersAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <pubkey.asc|fingerprint>",
+		Short: "Добавляет участника и перешифровывает файлы",
+		Long: `Импортирует публичный ключ участника, добавляет его в recipients
+и перешифровывает все файлы из secret_files.
+Примеры:
+  secret members add bob.pub.asc
+  secret members add 3AA5C34371567BD2A1B9C5E0F1D2C3B4A5968778
+  secret members add ~/.ssh/id_ed25519.pub # для бэкенда age`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			added, err := backend.AddRecipient(args[0])
+			if errors.Is(err, backends.ErrNotSupported) {
+				fmt.Printf("❌ Бэкенд %s не использует получателей: доступом управляет сам сервис\n", backend.Name())
+				os.Exit(1)
+			} else if err != nil {
+				fmt.Printf("❌ Ошибка: %v\n", err)
+				os.Exit(1)
+			}
+
+			seedRecipients(cfg, backend)
+			var fresh []string
+			for _, r := range added {
+				if hasMember(cfg.Recipients, r) {
+					fmt.Printf("ℹ️ %s уже является получателем\n", describeMember(backend, r))
+					continue
+				}
+				cfg.Recipients = append(cfg.Recipients, r)
+				fresh = append(fresh, r)
+			}
+			if len(fresh) == 0 {
+				return
+			}
+
+			if err := reencryptSecretFiles(cfg, backend); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println("Участник не добавлен: файлы и конфиг не изменены. Исправьте ошибку и повторите команду")
+				os.Exit(1)
+			}
+			if err := config.SaveConfig(cfg); err != nil {
+				fmt.Printf("❌ Ошибка сохранения конфига: %v\n", err)
+				os.Exit(1)
+			}
+			for _, r := range fresh {
+				fmt.Printf("✅ Добавлен получатель %s\n", describeMember(backend, r))
+			}
+		},
+	}
+}
+
+func membersRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <fingerprint|email|имя>",
+		Short: "Удаляет участника и перешифровывает файлы",
+		Long: `Удаляет участника из recipients и перешифровывает все файлы из
+secret_files без него. Выводит список файлов, которые участник мог
+прочитать: значения в них нужно сменить, так как старые версии
+остаются в истории git.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			seedRecipients(cfg, backend)
+			var kept, removed []string
+			for _, r := range cfg.Recipients {
+				if matchMember(backend, r, args[0]) {
+					removed = append(removed, r)
+				} else {
+					kept = append(kept, r)
+				}
+			}
+			// Участник может состоять и в группах доступа к отдельным файлам
+			groups := map[string][]string{}
+			for name, members := range cfg.Groups {
+				var rest []string
+				for _, r := range members {
+					if !matchMember(backend, r, args[0]) {
+						rest = append(rest, r)
+					} else if !hasMember(removed, r) {
+						removed = append(removed, r)
+					}
+				}
+				groups[name] = rest
+			}
+			if len(removed) == 0 {
+				fmt.Printf("❌ Получатель %s не найден. Список: secret members list\n", args[0])
+				os.Exit(1)
+			}
+			if len(kept) == 0 && len(cfg.Recipients) > 0 {
+				fmt.Println("❌ Нельзя удалить последнего получателя: файлы станет невозможно расшифровать")
+				os.Exit(1)
+			}
+
+			// Файлы, зашифрованные в том числе для удаляемого участника
+			var exposed []string
+			for _, c := range sharedConfigs(cfg) {
+				for _, enc := range getEncryptedFiles(c.SecretFiles.Patterns(), backend.Ext()) {
+					file := strings.TrimSuffix(enc, backend.Ext())
+					if hasMember(exposed, file) {
+						continue
+					}
+					recipients, err := c.FileRecipients(file)
+					if err != nil {
+						exposed = append(exposed, file)
+						continue
+					}
+					for _, r := range removed {
+						if hasMember(recipients, r) {
+							exposed = append(exposed, file)
+							break
+						}
+					}
+				}
+			}
+			descriptions := make([]string, len(removed))
+			for i, r := range removed {
+				descriptions[i] = describeMember(backend, r)
+			}
+
+			if len(cfg.Groups) > 0 {
+				cfg.Groups = groups
+			}
+			cfg.Recipients = kept
+
+			// Не оставляем файлы без единого получателя
+			for _, c := range sharedConfigs(cfg) {
+				for _, f := range c.SecretFiles {
+					if _, err := c.FileRecipients(f.Pattern); err != nil {
+						fmt.Printf("❌ %v: добавьте в группу другого участника перед удалением\n", err)
+						os.Exit(1)
+					}
+				}
+			}
+			if err := reencryptSecretFiles(cfg, backend); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println("Участник не удален: файлы и конфиг не изменены, файлы все еще доступны ему. Исправьте ошибку и повторите команду")
+				os.Exit(1)
+			}
+			if err := config.SaveConfig(cfg); err != nil {
+				fmt.Printf("❌ Ошибка сохранения конфига: %v\n", err)
+				os.Exit(1)
+			}
+			for _, d := range descriptions {
+				fmt.Printf("✅ Удален получатель %s\n", d)
+			}
+
+			if len(exposed) > 0 {
+				fmt.Println("\n⚠️ Эти файлы были доступны удаленному участнику — смените секреты в них:")
+				for _, file := range exposed {
+					fmt.Printf("  - %s\n", file)
+				}
+			}
+		},
+	}
+}
+
+func membersApproveCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "approve [request.yaml...]",
+		Short: "Одобряет запросы доступа из .secret/requests/",
+		Long: `Проверяет подписи запросов, созданных secret join, импортирует
+публичные ключи, добавляет их в recipients и перешифровывает файлы.
+Без аргументов обрабатываются все запросы из .secret/requests/.
+Перед одобрением сверьте отпечаток ключа с участником лично.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			files := args
+			if len(files) == 0 {
+				files, _ = filepath.Glob(filepath.Join(requestsDir, "*.yaml"))
+			}
+			if len(files) == 0 {
+				fmt.Println("ℹ️ Нет запросов доступа")
+				return
+			}
+
+			seedRecipients(cfg, backend)
+			var approved, names []string
+			for _, file := range files {
+				req, err := readAccessRequest(backend, file)
+				if err != nil {
+					fmt.Printf("❌ %s: %v\n", file, err)
+					continue
+				}
+
+				fmt.Printf("\n📨 Запрос %s\n", file)
+				fmt.Printf("Имя:         %s\n", req.Name)
+				fmt.Printf("Email:       %s\n", req.Email)
+				fmt.Printf("Fingerprint: %s\n", req.Fingerprint)
+				fmt.Printf("Создан:      %s\n", req.Created)
+				if hasMember(cfg.Recipients, req.Fingerprint) {
+					fmt.Println("ℹ️ Участник уже является получателем")
+					approved = append(approved, file)
+					continue
+				}
+				if !yes && !promptYesNo("Отпечаток совпадает с сообщенным участником? Одобрить (y/N): ", false) {
+					fmt.Println("⏭️ Пропущено")
+					continue
+				}
+
+				added, err := addRecipientFromKey(backend, []byte(req.PublicKey))
+				if err != nil {
+					fmt.Printf("❌ Ошибка импорта ключа: %v\n", err)
+					continue
+				}
+				// Получателем становится только показанный и проверенный по подписи ключ
+				var recipient string
+				for _, r := range added {
+					if sameMember(r, req.Fingerprint) {
+						recipient = r
+					}
+				}
+				if recipient == "" {
+					fmt.Printf("❌ %s: ключ %s не найден в публичном ключе запроса\n", file, req.Fingerprint)
+					continue
+				}
+				cfg.Recipients = append(cfg.Recipients, recipient)
+				approved = append(approved, file)
+				names = append(names, fmt.Sprintf("%s <%s>", req.Name, req.Email))
+			}
+			if len(approved) == 0 {
+				return
+			}
+
+			if len(names) > 0 {
+				if err := reencryptSecretFiles(cfg, backend); err != nil {
+					fmt.Printf("❌ %v\n", err)
+					fmt.Println("Запросы не одобрены: файлы и конфиг не изменены. Исправьте ошибку и повторите команду")
+					os.Exit(1)
+				}
+				if err := config.SaveConfig(cfg); err != nil {
+					fmt.Printf("❌ Ошибка сохранения конфига: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			for _, file := range approved {
+				os.Remove(file)
+			}
+			for _, name := range names {
+				fmt.Printf("✅ Одобрен доступ: %s\n", name)
+			}
+			fmt.Println("Закоммитьте изменения в .secret/ и зашифрованных файлах")
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Одобрить без подтверждения")
+	return cmd
+}
+
+// addRecipientFromKey добавляет получателя из публичного ключа в памяти
+func addRecipientFromKey(backend backends.Backend, key []byte) ([]string, error) {
+	tmp, err := os.CreateTemp("", "secret-member-*.asc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(key); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	return backend.AddRecipient(tmp.Name())
+}
+
+// seedRecipients переносит ключ gpg_key в recipients для старых конфигов,
+// чтобы при изменении списка владелец ключа проекта не потерял доступ
+func seedRecipients(cfg *config.Config, backend backends.Backend) {
+	if len(cfg.Recipients) > 0 || cfg.GPGKey == "" {
+		return
+	}
+	if info, err := backend.KeyInfo(cfg.GPGKey); err == nil && info.Fingerprint != "" {
+		cfg.Recipients = []string{info.Fingerprint}
+		return
+	}
+	cfg.Recipients = []string{cfg.GPGKey}
+}
+
+// reencryptSecretFiles перешифровывает файлы из secret_files основного
+// конфига и окружений, наследующих его получателей, для текущего набора
+// получателей. Файлы меняются все или ни один: при ошибке уже
+// перезаписанные файлы возвращаются к прежнему шифротексту.
+func reencryptSecretFiles(cfg *config.Config, backend backends.Backend) error {
+	staged, err := stageReencryption(cfg, backend)
+	if err != nil || len(staged) == 0 {
+		return err
+	}
+	fmt.Printf("🔁 Перешифровываем %d файлов...\n", len(staged))
+	return commitReencryption(cfg, backend, staged)
+}
+
+// reencryption — файл, расшифрованный в память перед перешифрованием
+type reencryption struct {
+	env       string // окружение, конфиг которого шифрует файл ("" — основной)
+	file      string
+	encrypted string
+	plaintext []byte
+	original  []byte // прежний шифротекст; nil, если файл еще не зашифрован
+}
+
+// stageReencryption расшифровывает в память файлы основного конфига и
+// окружений, наследующих его получателей, ничего не меняя на диске.
+// Источник — шифротекст: локальная открытая копия может быть старше
+// изменений, полученных из git. Открытый файл используется, только если
+// шифротекста еще нет.
+func stageReencryption(cfg *config.Config, backend backends.Backend) ([]*reencryption, error) {
+	seen := map[string]bool{}
+	var staged []*reencryption
+	for _, env := range sharedEnvironments(cfg) {
+		c, b, err := envBackend(cfg, backend, env)
+		if err != nil {
+			return nil, err
+		}
+		patterns := c.SecretFiles.Patterns()
+		files := getFilesToProcess(patterns)
+		for _, enc := range getEncryptedFiles(patterns, b.Ext()) {
+			files = append(files, strings.TrimSuffix(enc, b.Ext()))
+		}
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			r := &reencryption{env: env, file: file, encrypted: file + b.Ext()}
+			if r.original, err = os.ReadFile(r.encrypted); err == nil {
+				r.plaintext, err = b.DecryptBytes(r.encrypted)
+			} else if os.IsNotExist(err) {
+				r.plaintext, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("не удалось расшифровать %s: %v", file, err)
+			}
+			staged = append(staged, r)
+		}
+	}
+	return staged, nil
+}
+
+// commitReencryption шифрует подготовленные файлы для текущих получателей
+// конфига. Если хотя бы один файл не удалось зашифровать, уже обработанные
+// файлы восстанавливаются.
+func commitReencryption(cfg *config.Config, backend backends.Backend, staged []*reencryption) error {
+	envBackends := map[string]backends.Backend{}
+	for i, r := range staged {
+		b, ok := envBackends[r.env]
+		if !ok {
+			var err error
+			if _, b, err = envBackend(cfg, backend, r.env); err != nil {
+				restoreReencrypted(staged[:i])
+				return err
+			}
+			envBackends[r.env] = b
+		}
+		if err := b.EncryptBytes(r.file, r.plaintext); err != nil {
+			restoreReencrypted(staged[:i+1])
+			return fmt.Errorf("не удалось зашифровать %s: %v", r.file, err)
+		}
+	}
+	return nil
+}
+
+// restoreReencrypted возвращает на место прежний шифротекст файлов
+func restoreReencrypted(staged []*reencryption) {
+	for _, r := range staged {
+		var err error
+		if r.original == nil {
+			err = os.Remove(r.encrypted)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(r.encrypted, r.original, 0644)
+		}
+		if err != nil {
+			fmt.Printf("⚠️ Не удалось восстановить %s: %v\n", r.encrypted, err)
+		}
+	}
+}
+
+// envBackend возвращает конфиг окружения env и бэкенд для него;
+// для основного конфига ("") — сами cfg и backend
+func envBackend(cfg *config.Config, backend backends.Backend, env string) (*config.Config, backends.Backend, error) {
+	if env == "" {
+		return cfg, backend, nil
+	}
+	c, err := cfg.ForEnv(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := backends.New(c)
+	if err != nil {
+		return nil, nil, fmt.Errorf("окружение %s: %v", env, err)
+	}
+	return c, b, nil
+}
+
+// sharedEnvironments возвращает основной конфиг ("") и окружения без своих
+// gpg_key и recipients: они наследуют получателей основного конфига
+func sharedEnvironments(cfg *config.Config) []string {
+	envs := []string{""}
+	for _, name := range sortedEnvironments(cfg) {
+		env := cfg.Environments[name]
+		if env.GPGKey == "" && len(env.Recipients) == 0 {
+			envs = append(envs, name)
+		}
+	}
+	return envs
+}
+
+// sharedConfigs возвращает конфиги из sharedEnvironments
+func sharedConfigs(cfg *config.Config) []*config.Config {
+	var configs []*config.Config
+	for _, name := range sharedEnvironments(cfg) {
+		if envCfg, err := cfg.ForEnv(name); err == nil {
+			configs = append(configs, envCfg)
+		}
+	}
+	return configs
+}
+
+// sameMember сравнивает получателей без учета регистра и комментария SSH-ключа
+func sameMember(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	if len(fa) == 0 || len(fb) == 0 || !strings.EqualFold(fa[0], fb[0]) {
+		return false
+	}
+	if len(fa) >= 2 && len(fb) >= 2 {
+		return fa[1] == fb[1]
+	}
+	return true
+}
+
+func hasMember(recipients []string, r string) bool {
+	for _, existing := range recipients {
+		if sameMember(existing, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMember проверяет, подходит ли получатель под запрос: полный
+// отпечаток или ключ, ID ключа (окончание отпечатка), комментарий SSH-ключа,
+// имя или email владельца
+func matchMember(backend backends.Backend, r, query string) bool {
+	if sameMember(r, query) {
+		return true
+	}
+	if len(query) >= 8 && strings.HasSuffix(strings.ToUpper(r), strings.ToUpper(query)) {
+		return true
+	}
+	if fields := strings.Fields(r); len(fields) > 2 && strings.Join(fields[2:], " ") == query {
+		return true
+	}
+	if info, err := backend.KeyInfo(r); err == nil {
+		return strings.EqualFold(info.Email, query) || strings.EqualFold(info.Name, query)
+	}
+	return false
+}
+
+// describeMember возвращает получателя вместе с владельцем ключа, если он известен
+func describeMember(backend backends.Backend, r string) string {
+	info, err := backend.KeyInfo(r)
+	if err != nil || info.Name == "" || info.Name == r {
+		return r
+	}
+	if info.Email != "" && !strings.Contains(info.Name, info.Email) {
+		return fmt.Sprintf("%s (%s <%s>)", r, info.Name, info.Email)
+	}
+	return fmt.Sprintf("%s (%s)", r, info.Name)
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+// newTestProject создает проект openpgp во временной директории
+// с ключом владельца в gpg_key
+func newTestProject(t *testing.T) (*config.Config, backends.Backend) {
+	t.Helper()
+	t.Setenv("SECRET_OPENPGP_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	cfg := &config.Config{Backend: "openpgp", ProjectName: "test"}
+	b, err := backends.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GPGKey = testKey(t, b, "Owner")
+	return cfg, b
+}
+
+// testKey создает ключ и возвращает его отпечаток
+func testKey(t *testing.T, b backends.Backend, name string) string {
+	t.Helper()
+	keyID, err := b.GenerateKey(backends.KeyParams{Name: name, Email: name + "@example.com", Type: "ECC", Expire: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := b.KeyInfo(keyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Fingerprint
+}
+
+func encryptTestFile(t *testing.T, b backends.Backend, file, content string) []byte {
+	t.Helper()
+	if err := b.EncryptBytes(file, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := os.ReadFile(file + b.Ext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func TestReencryptSecretFilesAllOrNothing(t *testing.T) {
+	cfg, b := newTestProject(t)
+	owner := cfg.GPGKey
+	cfg.SecretFiles = config.SecretFiles{{Pattern: "a.env"}, {Pattern: "b.env", Groups: []string{"ops"}}}
+	cfg.Groups = map[string][]string{"ops": {owner}}
+	a := encryptTestFile(t, b, "a.env", "A=1\n")
+	encryptTestFile(t, b, "b.env", "B=1\n")
+
+	// a.env перешифровывается для нового участника, b.env — с ошибкой
+	cfg.Recipients = []string{owner, testKey(t, b, "Bob")}
+	cfg.Groups["ops"] = nil
+	if err := reencryptSecretFiles(cfg, b); err == nil {
+		t.Fatal("expected error for group without members")
+	}
+	if got, _ := os.ReadFile("a.env.gpg"); !bytes.Equal(got, a) {
+		t.Error("a.env.gpg rewritten despite failure")
+	}
+
+	cfg.Groups["ops"] = []string{owner}
+	if err := reencryptSecretFiles(cfg, b); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile("a.env.gpg"); bytes.Equal(got, a) {
+		t.Error("a.env.gpg not re-encrypted")
+	}
+}
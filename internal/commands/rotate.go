@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// @ rotate-key cmd
+func RotateKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Заменяет ключ проекта и перешифровывает все файлы",
+		Long: `Создает новый ключ проекта с параметрами, выбранными в secret init,
+расшифровывает все зашифрованные файлы старым ключом и шифрует их новым.
+Перешифровываются и файлы окружений без своего ключа, а старый ключ
+заменяется новым в recipients и в группах доступа.
+ID старого ключа сохраняется в key_history конфига.
+Операция атомарна: если хотя бы один файл не удалось обработать,
+файлы и конфиг остаются без изменений.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if cfg.GPGKey == "" {
+				fmt.Println("❌ В проекте не настроен ключ (gpg_key)")
+				fmt.Println("Для age и внешних хранилищ используйте: secret members")
+				os.Exit(1)
+			}
+
+			if err := rotateProjectKey(cfg, backend); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println("Файлы и конфиг не изменены")
+				os.Exit(1)
+			}
+		},
+	}
+	return cmd
+}
+
+// rotateProjectKey расшифровывает файлы в память, создает новый ключ и
+// перешифровывает файлы им. Если хотя бы один файл не удалось обработать,
+// файлы и конфиг возвращаются к прежнему состоянию.
+func rotateProjectKey(cfg *config.Config, backend backends.Backend) error {
+	//@ Расшифровываем файлы старым ключом (основной конфиг и окружения без своего ключа)
+	staged, err := stageReencryption(cfg, backend)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔓 Расшифровано %d файлов старым ключом\n", len(staged))
+
+	//@ Создаем новый ключ с параметрами из init
+	var params backends.KeyParams
+	if cfg.Key != nil {
+		fmt.Printf("\n⚙️  Параметры ключа: %s %d, срок %s\n", cfg.Key.Type, cfg.Key.Length, cfg.Key.Expire)
+		params = backends.KeyParams{Type: cfg.Key.Type, Length: cfg.Key.Length, Expire: cfg.Key.Expire}
+		params.Passphrase = promptKeyPassphrase()
+	} else {
+		params = promptKeyParams()
+	}
+	newKey, err := generateProjectKey(backend, cfg.ProjectName, params)
+	if err != nil {
+		return fmt.Errorf("ошибка создания ключа: %v", err)
+	}
+
+	oldKey, oldRecipients, oldGroups := cfg.GPGKey, cfg.Recipients, cfg.Groups
+	rollback := func() {
+		cfg.GPGKey, cfg.Recipients, cfg.Groups = oldKey, oldRecipients, oldGroups
+		if err := backend.DeleteKey(newKey); err != nil {
+			fmt.Printf("⚠️ Не удалось удалить новый ключ %s: %v\n", newKey, err)
+		}
+	}
+
+	//@ Подменяем ключ проекта в списке получателей и в группах
+	cfg.GPGKey = newKey
+	if len(oldRecipients) > 0 || len(oldGroups) > 0 {
+		newInfo, err := backend.KeyInfo(newKey)
+		if err != nil {
+			rollback()
+			return err
+		}
+		oldFingerprint := oldKey
+		if info, err := backend.KeyInfo(oldKey); err == nil {
+			oldFingerprint = info.Fingerprint
+		}
+		replace := func(members []string) []string {
+			if members == nil {
+				return nil
+			}
+			replaced := make([]string, len(members))
+			for i, r := range members {
+				if matchMember(backend, r, oldKey) || sameMember(r, oldFingerprint) {
+					r = newInfo.Fingerprint
+				}
+				replaced[i] = r
+			}
+			return replaced
+		}
+		cfg.Recipients = replace(oldRecipients)
+		if oldGroups != nil {
+			cfg.Groups = make(map[string][]string, len(oldGroups))
+			for name, members := range oldGroups {
+				cfg.Groups[name] = replace(members)
+			}
+		}
+	}
+
+	//@ Шифруем новым ключом; при ошибке commitReencryption восстанавливает файлы
+	fmt.Printf("\n🔒 Шифруем %d файлов новым ключом...\n", len(staged))
+	if err := commitReencryption(cfg, backend, staged); err != nil {
+		rollback()
+		return err
+	}
+
+	cfg.KeyHistory = append(cfg.KeyHistory, oldKey)
+	if err := config.SaveConfig(cfg); err != nil {
+		restoreReencrypted(staged)
+		cfg.KeyHistory = cfg.KeyHistory[:len(cfg.KeyHistory)-1]
+		rollback()
+		return fmt.Errorf("ошибка сохранения конфига: %v", err)
+	}
+
+	fmt.Printf("\n✅ Ключ проекта заменен: %s → %s\n", oldKey, newKey)
+	fmt.Println("Старый ключ остался в связке для чтения истории git.")
+	fmt.Println("🔑 Для резервной копии нового ключа выполните: secret export")
+	return nil
+}
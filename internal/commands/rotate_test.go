@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/pkg/config"
+)
+
+func TestRotateProjectKey(t *testing.T) {
+	cfg, b := newTestProject(t)
+	owner := cfg.GPGKey
+	bob := testKey(t, b, "Bob")
+	cfg.Key = &config.KeySpec{Type: "ECC", Expire: "0"}
+	cfg.Recipients = []string{owner, bob}
+	cfg.Groups = map[string][]string{"ops": {owner}}
+	cfg.SecretFiles = config.SecretFiles{{Pattern: ".env"}, {Pattern: "prod.env", Groups: []string{"ops"}}}
+	cfg.Environments = map[string]*config.Environment{
+		"staging": {SecretFiles: config.NewSecretFiles([]string{"staging.env"})},
+	}
+	encryptTestFile(t, b, ".env", "A=1\n")
+	encryptTestFile(t, b, "prod.env", "P=1\n")
+	stagingCfg, err := cfg.ForEnv("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staging, err := backends.New(stagingCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptTestFile(t, staging, "staging.env", "S=1\n")
+
+	// Парольная фраза нового ключа не запрашивается: stdin пуст
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = stdin }()
+
+	if err := rotateProjectKey(cfg, b); err != nil {
+		t.Fatal(err)
+	}
+	info, err := b.KeyInfo(cfg.GPGKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Recipients) != 2 || cfg.Recipients[0] != info.Fingerprint || cfg.Recipients[1] != bob {
+		t.Errorf("recipients = %v", cfg.Recipients)
+	}
+	if ops := cfg.Groups["ops"]; len(ops) != 1 || ops[0] != info.Fingerprint {
+		t.Errorf("ops = %v, want new key %s", ops, info.Fingerprint)
+	}
+
+	// Без старых ключей все файлы читаются новым ключом
+	for _, key := range []string{owner, bob} {
+		if err := b.DeleteKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, want := range map[string]string{".env": "A=1\n", "prod.env": "P=1\n", "staging.env": "S=1\n"} {
+		got, err := b.DecryptBytes(file + b.Ext())
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+		} else if string(got) != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+}
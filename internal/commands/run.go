@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Avdushin/secret/internal/envfile"
+	"github.com/spf13/cobra"
+)
+
+// @ run cmd
+func RunCmd() *cobra.Command {
+	var files []string
+	var envs []string
+	var override bool
+
+	cmd := &cobra.Command{
+		Use:   "run [flags] -- <command> [args...]",
+		Short: "Запускает команду с секретами в переменных окружения",
+		Long: `Расшифровывает .env-файлы из secret_files в память и передает
+переменные дочернему процессу. Открытые файлы на диск не записываются.
+Сигналы пересылаются процессу, код выхода сохраняется.
+По умолчанию уже заданные переменные окружения имеют приоритет,
+с флагом --override их перекрывают значения из секретов.
+Примеры:
+  secret run -- npm start
+  secret run --env prod -- ./migrate
+  secret run -f .env.gpg -f local.env.gpg --override -- make test`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			vars, err := loadSecretEnv(envs, files)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(runWithEnv(args, mergeEnv(os.Environ(), vars, override)))
+		},
+	}
+
+	// Флаги после имени команды относятся к ней, а не к secret
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().StringArrayVarP(&files, "file", "f", nil, "Зашифрованный .env-файл (можно несколько; по умолчанию все .env из secret_files)")
+	cmd.Flags().StringArrayVarP(&envs, "env", "e", nil, "Окружение из environments (можно несколько)")
+	cmd.Flags().BoolVar(&override, "override", false, "Значения из секретов перекрывают существующие переменные")
+	return cmd
+}
+
+// loadSecretEnv расшифровывает .env-файлы окружений в память.
+// Более поздние файлы перекрывают значения более ранних.
+func loadSecretEnv(envs, files []string) ([]envfile.Entry, error) {
+	if len(envs) == 0 {
+		envs = []string{""}
+	}
+	if len(files) > 0 && len(envs) > 1 {
+		return nil, fmt.Errorf("--file нельзя сочетать с несколькими --env")
+	}
+
+	var vars []envfile.Entry
+	for _, env := range envs {
+		cfg, backend, err := loadEnvBackend(env)
+		if err != nil {
+			return nil, err
+		}
+
+		targets := files
+		if len(targets) == 0 {
+			for _, enc := range getEncryptedFiles(cfg.SecretFiles.Patterns(), backend.Ext()) {
+				if envfile.IsEnvFile(strings.TrimSuffix(enc, backend.Ext())) {
+					targets = append(targets, enc)
+				}
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("не найдено зашифрованных .env-файлов (укажите --file)")
+		}
+
+		for _, file := range targets {
+			if !strings.HasSuffix(file, backend.Ext()) {
+				file += backend.Ext()
+			}
+			content, err := backend.DecryptBytes(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+			vars = append(vars, envfile.Parse(string(content))...)
+		}
+	}
+	return vars, nil
+}
+
+// mergeEnv добавляет переменные из секретов к окружению процесса
+func mergeEnv(environ []string, vars []envfile.Entry, override bool) []string {
+	index := map[string]int{}
+	result := make([]string, 0, len(environ)+len(vars))
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		index[key] = len(result)
+		result = append(result, kv)
+	}
+
+	fromSecrets := map[string]bool{}
+	for _, v := range vars {
+		kv := v.Key + "=" + v.Value
+		i, exists := index[v.Key]
+		switch {
+		case !exists:
+			index[v.Key] = len(result)
+			result = append(result, kv)
+			fromSecrets[v.Key] = true
+		case override || fromSecrets[v.Key]:
+			// Секреты всегда перекрывают друг друга по порядку файлов
+			result[i] = kv
+		}
+	}
+	return result
+}
+
+// runWithEnv запускает команду, пересылает ей сигналы и возвращает код выхода
+func runWithEnv(args, env []string) int {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		fmt.Printf("❌ Не удалось запустить %s: %v\n", args[0], err)
+		return 127
+	}
+
+	go func() {
+		for sig := range signals {
+			_ = child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	// Процесс завершен сигналом: код как у shell (128 + номер сигнала)
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
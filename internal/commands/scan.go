@@ -0,0 +1,437 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/backends"
+	"github.com/Avdushin/secret/internal/fields"
+	"github.com/Avdushin/secret/pkg/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// @ scan cmd
+func ScanCmd() *cobra.Command {
+	var history bool
+	var discover bool
+	var minLength int
+
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Ищет утечки значений секретов в репозитории",
+		Long: `Расшифровывает все secret_files в память и ищет их значения — как есть,
+в base64 и в URL-кодировке — в файлах рабочей директории, а с --history
+во всех объектах истории git. Находит скопированные секреты, которые
+пропускают сканеры на регулярных выражениях. Сами значения не выводятся.
+Код выхода 1, если найдены утечки.
+С --discover ищет в файлах вне secret_files приватные ключи, ключи AWS,
+JWT, токены, строки подключения с паролем и строки с высокой энтропией
+и предлагает добавить найденные файлы в secret_files.
+Примеры:
+  secret scan --history
+  secret scan --discover`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, backend, err := loadBackend()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if discover {
+				runDiscover(cfg, backend.Ext())
+				return
+			}
+
+			needles, files := collectNeedles(cfg, backend.Ext(), minLength)
+			if len(needles) == 0 {
+				fmt.Println("ℹ️ Не найдено значений для поиска")
+				return
+			}
+			fmt.Printf("🔍 Ищем значения из %d файлов в рабочей директории...\n", files)
+			leaks := scanWorkTree(cfg, backend.Ext(), needles)
+
+			if history {
+				fmt.Println("🔍 Ищем в истории git...")
+				found, err := scanHistory(needles)
+				if err != nil {
+					fmt.Printf("❌ Ошибка чтения истории git: %v\n", err)
+					os.Exit(1)
+				}
+				leaks = append(leaks, found...)
+			}
+
+			sort.SliceStable(leaks, func(i, j int) bool {
+				if leaks[i].location != leaks[j].location {
+					return leaks[i].location < leaks[j].location
+				}
+				return leaks[i].line < leaks[j].line
+			})
+			if len(leaks) == 0 {
+				fmt.Println("✅ Утечек не найдено")
+				return
+			}
+			fmt.Printf("\n❌ Найдены утечки (%d):\n", len(leaks))
+			for _, l := range leaks {
+				fmt.Printf("  %s\n", l)
+			}
+			fmt.Println("\nУдалите значения из файлов и смените утекшие секреты.")
+			os.Exit(1)
+		},
+	}
+
+	cmd.Flags().BoolVar(&history, "history", false, "Искать также во всех объектах истории git")
+	cmd.Flags().BoolVar(&discover, "discover", false, "Искать похожие на секреты строки в файлах вне secret_files")
+	cmd.Flags().IntVar(&minLength, "min-length", 6, "Не искать значения короче указанной длины")
+	return cmd
+}
+
+// runDiscover выводит файлы с похожими на секреты строками и предлагает
+// добавить их в secret_files. Без терминала завершается с кодом 1.
+func runDiscover(cfg *config.Config, ext string) {
+	findings := discoverSecrets(cfg, ext)
+	if len(findings) == 0 {
+		fmt.Println("✅ Похожих на секреты строк вне secret_files не найдено")
+		return
+	}
+
+	fmt.Println("🔎 Возможные секреты вне secret_files:")
+	var files []string
+	for i, f := range findings {
+		if i == 0 || findings[i-1].file != f.file {
+			fmt.Printf("\n  %s\n", f.file)
+			files = append(files, f.file)
+		}
+		fmt.Printf("    %d: %s\n", f.line, f.kind)
+	}
+	fmt.Println()
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("Добавьте эти файлы в secret_files в .secret/config.yaml")
+		os.Exit(1)
+	}
+	var added []string
+	for _, file := range files {
+		if promptYesNo(fmt.Sprintf("Добавить %s в secret_files? (y/N): ", file), false) {
+			added = append(added, filepath.ToSlash(file))
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+
+	cfg.SecretFiles = append(cfg.SecretFiles, config.NewSecretFiles(added)...)
+	if err := config.SaveConfig(cfg); err != nil {
+		fmt.Printf("❌ Ошибка сохранения конфига: %v\n", err)
+		os.Exit(1)
+	}
+	updateGitignore(ext)
+	fmt.Printf("✅ Добавлено в secret_files: %s\n", strings.Join(added, ", "))
+	if out, _ := gitOutput(append([]string{"ls-files", "--"}, added...)...); len(out) > 0 {
+		fmt.Println("⚠️ Эти файлы уже в git. Уберите их из индекса: git rm --cached <файл>")
+	}
+	fmt.Println("🔒 Зашифруйте их: secret encrypt")
+}
+
+// needle — искомая строка: значение секрета или его закодированная форма
+type needle struct {
+	text []byte
+	key  string
+	file string
+	form string
+}
+
+// leak — найденное вхождение значения
+type leak struct {
+	location string
+	line     int
+	needle   needle
+}
+
+func (l leak) String() string {
+	key := l.needle.key
+	if key == "" {
+		key = "содержимое"
+	}
+	s := fmt.Sprintf("%s:%d — %s из %s", l.location, l.line, key, l.needle.file)
+	if l.needle.form != "" {
+		s += " (" + l.needle.form + ")"
+	}
+	return s
+}
+
+// collectNeedles расшифровывает secret_files основного конфига и окружений
+// и возвращает значения для поиска и число прочитанных файлов
+func collectNeedles(cfg *config.Config, ext string, minLength int) ([]needle, int) {
+	patterns := allSecretPatterns(cfg)
+	var names []string
+	for _, file := range getEncryptedFiles(patterns, ext) {
+		names = append(names, strings.TrimSuffix(file, ext))
+	}
+	// Под фильтром secret git открытые файлы лежат в рабочей директории
+	if gitFilterInstalled() {
+		names = append(names, getFilesToProcess(patterns)...)
+	}
+
+	seen := map[string]bool{}
+	seenText := map[string]bool{}
+	var needles []needle
+	files := 0
+	for _, file := range names {
+		if seen[file] || strings.Contains(filepath.Base(file), ".example.") {
+			continue
+		}
+		seen[file] = true
+
+		plaintext, err := readSecretFile(file, ext)
+		if err != nil {
+			fmt.Printf("⚠️ %s пропущен: %v\n", file, err)
+			continue
+		}
+		files++
+
+		for _, value := range secretValues(cfg, file, plaintext) {
+			key, text := value[0], value[1]
+			if len(text) < minLength {
+				continue
+			}
+			for _, form := range encodedForms(text) {
+				if !seenText[form[1]] {
+					seenText[form[1]] = true
+					needles = append(needles, needle{text: []byte(form[1]), key: key, file: file, form: form[0]})
+				}
+			}
+		}
+	}
+	return needles, files
+}
+
+// readSecretFile возвращает открытый текст файла: расшифровывает file+ext
+// или читает открытый файл, если шифротекста нет (фильтр secret git)
+func readSecretFile(file, ext string) ([]byte, error) {
+	if _, err := os.Stat(file + ext); err != nil {
+		return os.ReadFile(file)
+	}
+	_, backend, err := loadFileBackend(file)
+	if err != nil {
+		return nil, err
+	}
+	return backend.DecryptBytes(file + ext)
+}
+
+// secretValues возвращает значения файла (с учетом меток) парами
+// {ключ, текст}. Ключи могут повторяться: одинаковые имена во вложенных
+// объектах и элементы списков. Файл неизвестного формата считается одним
+// значением с пустым ключом.
+func secretValues(cfg *config.Config, file string, plaintext []byte) [][2]string {
+	found, err := fields.Scan(fields.DetectFormat(file), plaintext)
+	if err != nil {
+		return [][2]string{{"", strings.TrimSpace(string(plaintext))}}
+	}
+	var values [][2]string
+	for _, v := range found {
+		if cfg.Markers.Marked(v.Key, v.Comment) {
+			values = append(values, [2]string{v.Key, fields.Unquote(plaintext[v.Start:v.End])})
+		}
+	}
+	return values
+}
+
+// encodedForms возвращает значение и его формы в base64 и URL-кодировке
+// парами {название формы, текст}. base64 без выравнивания находит
+// и выровненные вхождения.
+func encodedForms(text string) [][2]string {
+	forms := [][2]string{{"", text}}
+	for _, f := range [][2]string{
+		{"base64", base64.RawStdEncoding.EncodeToString([]byte(text))},
+		{"base64url", base64.RawURLEncoding.EncodeToString([]byte(text))},
+		{"url", url.QueryEscape(text)},
+		{"url", url.PathEscape(text)},
+	} {
+		duplicate := false
+		for _, existing := range forms {
+			duplicate = duplicate || existing[1] == f[1]
+		}
+		if !duplicate {
+			forms = append(forms, f)
+		}
+	}
+	return forms
+}
+
+// scanWorkTree ищет значения в файлах рабочей директории, кроме самих
+// секретных файлов и шифротекста. В git-репозитории проверяются файлы
+// индекса и неигнорируемые новые файлы.
+func scanWorkTree(cfg *config.Config, ext string, needles []needle) []leak {
+	var leaks []leak
+	for _, file := range workTreeFiles() {
+		if strings.HasSuffix(file, ext) || (matchesSecretFiles(cfg, file) && !strings.Contains(filepath.Base(file), ".example.")) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil || isBinary(content) || backends.LooksEncrypted(content) {
+			continue
+		}
+		leaks = append(leaks, findLeaks(file, content, needles)...)
+	}
+	return leaks
+}
+
+func workTreeFiles() []string {
+	var files []string
+	if out, err := gitOutput("ls-files", "-z", "--cached", "--others", "--exclude-standard"); err == nil {
+		for _, file := range strings.Split(string(out), "\x00") {
+			if file != "" {
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+	_ = filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != "." && vendorDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// scanHistory ищет значения во всех blob-объектах, достижимых из веток и тегов
+func scanHistory(needles []needle) ([]leak, error) {
+	out, err := gitOutput("rev-list", "--all", "--objects")
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]string{}
+	var ids []string
+	for _, line := range strings.Split(string(out), "\n") {
+		id, path, ok := strings.Cut(line, " ")
+		if ok && path != "" {
+			paths[id] = path
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var leaks []leak
+	err = catBlobs(ids, func(id string, content []byte) error {
+		if isBinary(content) || backends.LooksEncrypted(content) {
+			return nil
+		}
+		found := findLeaks(paths[id], content, needles)
+		if len(found) == 0 {
+			return nil
+		}
+		commit := blobCommit(id)
+		for i := range found {
+			found[i].location = fmt.Sprintf("%s@%s", found[i].location, commit)
+		}
+		leaks = append(leaks, found...)
+		return nil
+	})
+	return leaks, err
+}
+
+// catBlobs читает объекты ids через git cat-file --batch и передает blob-объекты
+// в visit. Если чтение прервано (ошибкой visit или вывода git), процесс git
+// завершается: иначе он блокируется на записи в заполненный pipe, и Wait не вернется.
+func catBlobs(ids []string, visit func(id string, content []byte) error) error {
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Stdin = strings.NewReader(strings.Join(ids, "\n") + "\n")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	readErr := func() error {
+		reader := bufio.NewReader(stdout)
+		for range ids {
+			var id, kind string
+			var size int
+			header, err := reader.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Sscanf(header, "%s %s %d", &id, &kind, &size); err != nil {
+				// Объект не найден: "<id> missing", содержимого нет
+				continue
+			}
+			content := make([]byte, size+1)
+			if _, err := io.ReadFull(reader, content); err != nil {
+				return err
+			}
+			if kind != "blob" {
+				continue
+			}
+			if err := visit(id, content[:size]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if readErr != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return readErr
+	}
+	return cmd.Wait()
+}
+
+// blobCommit возвращает первый коммит, в котором появился объект
+func blobCommit(id string) string {
+	out, err := gitOutput("log", "--all", "--format=%h", "--find-object="+id)
+	lines := strings.Fields(string(out))
+	if err != nil || len(lines) == 0 {
+		return id[:7]
+	}
+	return lines[len(lines)-1]
+}
+
+func findLeaks(location string, content []byte, needles []needle) []leak {
+	var leaks []leak
+	for _, n := range needles {
+		for offset := 0; ; {
+			idx := bytes.Index(content[offset:], n.text)
+			if idx < 0 {
+				break
+			}
+			pos := offset + idx
+			leaks = append(leaks, leak{
+				location: location,
+				line:     bytes.Count(content[:pos], []byte("\n")) + 1,
+				needle:   n,
+			})
+			offset = pos + len(n.text)
+		}
+	}
+	return leaks
+}
+
+// isBinary считает файл двоичным, если в начале есть нулевой байт (как git)
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
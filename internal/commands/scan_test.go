@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRepo создает git-репозиторий во временной директории и коммитит files
+func newTestRepo(t *testing.T, files map[string]string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestScanHistory(t *testing.T) {
+	newTestRepo(t, map[string]string{"notes.txt": "pass: hunter22\n", "other.txt": "nothing\n"})
+	leaks, err := scanHistory([]needle{{text: []byte("hunter22"), key: "DB_PASSWORD", file: ".env"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaks) != 1 || !strings.HasPrefix(leaks[0].location, "notes.txt@") || leaks[0].line != 1 {
+		t.Errorf("leaks = %v", leaks)
+	}
+}
+
+func TestCatBlobsStopsEarly(t *testing.T) {
+	// Вывод git намного больше буфера pipe (64 КБ)
+	files := map[string]string{}
+	for i := 0; i < 8; i++ {
+		files[fmt.Sprintf("big%d.txt", i)] = strings.Repeat(fmt.Sprintf("line %d\n", i), 100000)
+	}
+	newTestRepo(t, files)
+	out, err := gitOutput("rev-list", "--all", "--objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		ids = append(ids, strings.Fields(line)[0])
+	}
+
+	stop := errors.New("stop")
+	done := make(chan error, 1)
+	go func() {
+		done <- catBlobs(ids, func(id string, content []byte) error { return stop })
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, stop) {
+			t.Errorf("err = %v, want stop", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("catBlobs hung after stopping early")
+	}
+}
@@ -0,0 +1,133 @@
+// Package envfile разбирает и формирует файлы в формате .env
+package envfile
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Entry — пара ключ/значение из .env файла
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// IsEnvFile определяет по имени, является ли файл .env-файлом
+// (.env, .env.local, dev.env и т.п.)
+func IsEnvFile(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env")
+}
+
+// Parse разбирает содержимое .env файла. Пустые строки и комментарии пропускаются,
+// префикс export допускается, значения в кавычках раскавычиваются.
+func Parse(content string) []Entry {
+	var entries []Entry
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:eq])
+		raw := strings.TrimSpace(line[eq+1:])
+
+		// Многострочные значения в двойных кавычках
+		if strings.HasPrefix(raw, `"`) && !closedQuote(raw[1:], '"') {
+			for i+1 < len(lines) {
+				i++
+				raw += "\n" + lines[i]
+				if closedQuote(lines[i], '"') {
+					break
+				}
+			}
+		}
+		entries = append(entries, Entry{Key: key, Value: unquote(raw)})
+	}
+	return entries
+}
+
+// closedQuote проверяет, есть ли в строке неэкранированная закрывающая кавычка
+func closedQuote(s string, q byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == q {
+			return true
+		}
+	}
+	return false
+}
+
+func unquote(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	switch raw[0] {
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case 'r':
+					sb.WriteByte('\r')
+				default:
+					sb.WriteByte(raw[i])
+				}
+				continue
+			}
+			if c == '"' {
+				break
+			}
+			sb.WriteByte(c)
+		}
+		return sb.String()
+	case '\'':
+		if end := strings.IndexByte(raw[1:], '\''); end >= 0 {
+			return raw[1 : end+1]
+		}
+		return raw[1:]
+	}
+	// Инлайн-комментарий у значения без кавычек
+	if idx := strings.Index(raw, " #"); idx >= 0 {
+		raw = raw[:idx]
+	}
+	return strings.TrimSpace(raw)
+}
+
+// Quote возвращает значение в виде, пригодном для записи в .env
+func Quote(value string) string {
+	if value == "" {
+		return ""
+	}
+	if !strings.ContainsAny(value, " \t\n\r#\"'\\$`") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// Format формирует содержимое .env файла из списка пар
+func Format(entries []Entry) string {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(e.Key)
+		sb.WriteByte('=')
+		sb.WriteString(Quote(e.Value))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
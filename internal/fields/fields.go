@@ -0,0 +1,337 @@
+// Package fields читает, изменяет и сливает по ключам значения в .env, JSON
+// и YAML файлах, сохраняя порядок ключей и (где возможно) комментарии, и
+// находит значения в .env, INI, JSON, YAML и TOML для шифрования и .example
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Avdushin/secret/internal/envfile"
+	"gopkg.in/yaml.v3"
+)
+
+// Format — формат файла с секретами
+type Format int
+
+const (
+	Unknown Format = iota
+	Env
+	JSON
+	YAML
+	TOML
+	INI
+)
+
+func (f Format) String() string {
+	switch f {
+	case Env:
+		return "env"
+	case JSON:
+		return "json"
+	case YAML:
+		return "yaml"
+	case TOML:
+		return "toml"
+	case INI:
+		return "ini"
+	}
+	return "unknown"
+}
+
+// Editable сообщает, поддерживают ли Get и Set этот формат
+func (f Format) Editable() bool {
+	return f == Env || f == JSON || f == YAML
+}
+
+// DetectFormat определяет формат по имени файла
+func DetectFormat(name string) Format {
+	if envfile.IsEnvFile(name) {
+		return Env
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	case ".ini":
+		return INI
+	}
+	return Unknown
+}
+
+// Get возвращает значение ключа. Для JSON и YAML ключ — путь через точку
+// (database.password, servers.0.host); вложенные объекты возвращаются целиком.
+func Get(format Format, content []byte, key string) (string, error) {
+	switch format {
+	case Env:
+		for _, e := range envfile.Parse(string(content)) {
+			if e.Key == key {
+				return e.Value, nil
+			}
+		}
+		return "", fmt.Errorf("ключ %s не найден", key)
+	case JSON, YAML:
+		root, err := parseTree(content)
+		if err != nil {
+			return "", err
+		}
+		node, err := lookup(root, splitPath(key), false)
+		if err != nil {
+			return "", err
+		}
+		if node.Kind == yaml.ScalarNode {
+			return node.Value, nil
+		}
+		out, err := encodeTree(format, node)
+		return strings.TrimRight(string(out), "\n"), err
+	}
+	return "", fmt.Errorf("формат файла не поддерживается (доступны: env, json, yaml)")
+}
+
+// Set устанавливает значение ключа и возвращает новое содержимое файла.
+// Отсутствующие ключи и промежуточные объекты создаются.
+func Set(format Format, content []byte, key, value string) ([]byte, error) {
+	switch format {
+	case Env:
+		return setEnv(content, key, value), nil
+	case JSON, YAML:
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, err
+		}
+		node, err := lookup(root, splitPath(key), true)
+		if err != nil {
+			return nil, err
+		}
+		setScalar(node, value)
+		return encodeTree(format, root)
+	}
+	return nil, fmt.Errorf("формат файла не поддерживается (доступны: env, json, yaml)")
+}
+
+// setEnv заменяет значение в строке с ключом, не трогая остальные строки
+func setEnv(content []byte, key, value string) []byte {
+	line := key + "=" + envfile.Quote(value)
+	lines := strings.Split(string(content), "\n")
+	if start, end, export := envRange(lines, key); start >= 0 {
+		if export {
+			line = "export " + line
+		}
+		lines = append(lines[:start], append([]string{line}, lines[end+1:]...)...)
+		return []byte(strings.Join(lines, "\n"))
+	}
+
+	text := string(content)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return []byte(text + line + "\n")
+}
+
+// removeEnv удаляет строки ключа
+func removeEnv(content []byte, key string) []byte {
+	lines := strings.Split(string(content), "\n")
+	if start, end, _ := envRange(lines, key); start >= 0 {
+		lines = append(lines[:start], lines[end+1:]...)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// envRange находит первую и последнюю строку ключа (многострочное значение
+// в кавычках занимает несколько строк); start < 0, если ключа нет
+func envRange(lines []string, key string) (start, end int, export bool) {
+	for i, l := range lines {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(l), "export ")
+		eq := strings.Index(trimmed, "=")
+		if eq <= 0 || strings.TrimSpace(trimmed[:eq]) != key {
+			continue
+		}
+		end = i
+		raw := strings.TrimSpace(trimmed[eq+1:])
+		if strings.HasPrefix(raw, `"`) && !strings.Contains(raw[1:], `"`) {
+			for end+1 < len(lines) {
+				end++
+				if strings.Contains(lines[end], `"`) {
+					break
+				}
+			}
+		}
+		return i, end, strings.HasPrefix(strings.TrimSpace(l), "export ")
+	}
+	return -1, -1, false
+}
+
+func splitPath(key string) []string {
+	return strings.Split(key, ".")
+}
+
+// parseTree разбирает JSON или YAML (JSON — подмножество YAML)
+func parseTree(content []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("ошибка разбора файла: %v", err)
+	}
+	if doc.Kind == 0 {
+		// Пустой файл
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	return &doc, nil
+}
+
+// lookup находит узел по пути; при create недостающие узлы создаются
+func lookup(node *yaml.Node, path []string, create bool) (*yaml.Node, error) {
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+	for i, part := range path {
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == part {
+					next = node.Content[j+1]
+					break
+				}
+			}
+			if next == nil {
+				if !create {
+					return nil, fmt.Errorf("ключ %s не найден", strings.Join(path[:i+1], "."))
+				}
+				next = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+				if i < len(path)-1 {
+					next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, next)
+			}
+			node = next
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node.Content) {
+				return nil, fmt.Errorf("неверный индекс %s в %s", part, strings.Join(path[:i], "."))
+			}
+			node = node.Content[idx]
+		default:
+			return nil, fmt.Errorf("%s не является объектом", strings.Join(path[:i], "."))
+		}
+	}
+	return node, nil
+}
+
+// setScalar записывает строку, сохраняя тип существующего значения,
+// если новое значение ему соответствует (числа, логические значения)
+func setScalar(node *yaml.Node, value string) {
+	tag := "!!str"
+	if node.Kind == yaml.ScalarNode && node.Tag != "!!str" && node.Tag != "" {
+		probe := yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		if probe.ShortTag() == node.ShortTag() {
+			tag = node.Tag
+		}
+	}
+	*node = yaml.Node{
+		Kind:        yaml.ScalarNode,
+		Tag:         tag,
+		Value:       value,
+		HeadComment: node.HeadComment,
+		LineComment: node.LineComment,
+		FootComment: node.FootComment,
+	}
+	if tag == "!!str" && strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+}
+
+func encodeTree(format Format, node *yaml.Node) ([]byte, error) {
+	if format == JSON {
+		var buf bytes.Buffer
+		if node.Kind == yaml.DocumentNode {
+			node = node.Content[0]
+		}
+		if err := writeJSON(&buf, node, ""); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSON печатает дерево как JSON с отступом в 2 пробела,
+// сохраняя исходный порядок ключей
+func writeJSON(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	inner := indent + "  "
+	switch node.Kind {
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			buf.WriteString(inner)
+			writeJSONString(buf, node.Content[i].Value)
+			buf.WriteString(": ")
+			if err := writeJSON(buf, node.Content[i+1], inner); err != nil {
+				return err
+			}
+			if i+2 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			buf.WriteString(inner)
+			if err := writeJSON(buf, item, inner); err != nil {
+				return err
+			}
+			if i+1 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool", "!!null":
+			buf.WriteString(node.Value)
+		default:
+			writeJSONString(buf, node.Value)
+		}
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias, indent)
+	default:
+		return fmt.Errorf("неподдерживаемый узел JSON")
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Write(bytes.TrimRight(tmp.Bytes(), "\n"))
+}
@@ -0,0 +1,210 @@
+package fields
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Avdushin/secret/internal/envfile"
+	"gopkg.in/yaml.v3"
+)
+
+// Merge сливает три версии файла по ключам: изменения разных ключей
+// объединяются, а ключи, измененные по-разному с обеих сторон, возвращаются
+// в conflicts (в результате для них остается значение ours). Пустой base —
+// файл добавлен в обеих ветках. Для JSON и YAML ключ — путь через точку;
+// списки сливаются целиком.
+func Merge(format Format, base, ours, theirs []byte) (merged []byte, conflicts []string, err error) {
+	switch {
+	case bytes.Equal(ours, theirs), bytes.Equal(base, theirs):
+		return ours, nil, nil
+	case bytes.Equal(base, ours):
+		return theirs, nil, nil
+	}
+
+	switch format {
+	case Env:
+		merged, conflicts = mergeEnv(base, ours, theirs)
+		return merged, conflicts, nil
+	case JSON, YAML:
+		var trees [3]*yaml.Node
+		for i, content := range [][]byte{base, ours, theirs} {
+			doc, err := parseTree(content)
+			if err != nil {
+				return nil, nil, err
+			}
+			trees[i] = doc.Content[0]
+		}
+		b, o, t := trees[0], trees[1], trees[2]
+		if o.Kind != yaml.MappingNode || t.Kind != yaml.MappingNode || b.Kind != yaml.MappingNode {
+			// Корень не объект: сливать можно только целиком
+			if nodeEqual(o, b) {
+				return theirs, nil, nil
+			}
+			return ours, []string{"."}, nil
+		}
+		conflicts = mergeMapping(o, b, t, "")
+		merged, err = encodeTree(format, o)
+		return merged, conflicts, err
+	}
+	return nil, nil, fmt.Errorf("формат %s не поддерживает слияние (доступны: env, json, yaml)", format)
+}
+
+// envSide — значения .env файла и порядок ключей
+type envSide struct {
+	values map[string]string
+	keys   []string
+}
+
+func parseEnvSide(content []byte) envSide {
+	side := envSide{values: map[string]string{}}
+	for _, e := range envfile.Parse(string(content)) {
+		if _, ok := side.values[e.Key]; !ok {
+			side.keys = append(side.keys, e.Key)
+		}
+		side.values[e.Key] = e.Value
+	}
+	return side
+}
+
+// mergeEnv применяет к ours изменения theirs построчно, поэтому
+// комментарии и порядок строк ours сохраняются
+func mergeEnv(base, ours, theirs []byte) ([]byte, []string) {
+	b, o, t := parseEnvSide(base), parseEnvSide(ours), parseEnvSide(theirs)
+	same := func(x, y envSide, key string) bool {
+		xv, xok := x.values[key]
+		yv, yok := y.values[key]
+		return xok == yok && xv == yv
+	}
+
+	keys := o.keys
+	for _, key := range t.keys {
+		if _, ok := o.values[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var conflicts []string
+	merged := ours
+	for _, key := range keys {
+		switch {
+		case same(o, t, key), same(t, b, key):
+		case same(o, b, key):
+			if value, ok := t.values[key]; ok {
+				merged = setEnv(merged, key, value)
+			} else {
+				merged = removeEnv(merged, key)
+			}
+		default:
+			conflicts = append(conflicts, key)
+		}
+	}
+	return merged, conflicts
+}
+
+// mergeMapping применяет к ours изменения theirs относительно base.
+// Вложенные объекты сливаются рекурсивно.
+func mergeMapping(ours, base, theirs *yaml.Node, path string) []string {
+	keys := mappingKeys(ours)
+	for _, key := range mappingKeys(theirs) {
+		if mappingValue(ours, key) == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	var conflicts []string
+	for _, key := range keys {
+		o, b, t := mappingValue(ours, key), mappingValue(base, key), mappingValue(theirs, key)
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		switch {
+		case isMapping(o) && isMapping(t) && (b == nil || isMapping(b)):
+			conflicts = append(conflicts, mergeMapping(o, b, t, keyPath)...)
+		case nodeEqual(o, t), nodeEqual(t, b):
+		case nodeEqual(o, b):
+			setMappingValue(ours, theirs, key)
+		default:
+			conflicts = append(conflicts, keyPath)
+		}
+	}
+	return conflicts
+}
+
+func isMapping(node *yaml.Node) bool {
+	return node != nil && node.Kind == yaml.MappingNode
+}
+
+func mappingKeys(node *yaml.Node) []string {
+	var keys []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// mappingValue возвращает значение ключа или nil (в том числе для node == nil)
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue переносит ключ из from в to: заменяет, добавляет
+// или удаляет его вместе с комментариями
+func setMappingValue(to, from *yaml.Node, key string) {
+	var pair []*yaml.Node
+	for i := 0; i+1 < len(from.Content); i += 2 {
+		if from.Content[i].Value == key {
+			pair = from.Content[i : i+2]
+			break
+		}
+	}
+	for i := 0; i+1 < len(to.Content); i += 2 {
+		if to.Content[i].Value == key {
+			if pair == nil {
+				to.Content = append(to.Content[:i], to.Content[i+2:]...)
+			} else {
+				to.Content[i+1] = pair[1]
+			}
+			return
+		}
+	}
+	if pair != nil {
+		to.Content = append(to.Content, pair...)
+	}
+}
+
+// nodeEqual сравнивает значения узлов без учета стиля и комментариев
+func nodeEqual(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind == yaml.AliasNode {
+		a = a.Alias
+	}
+	if b.Kind == yaml.AliasNode {
+		b = b.Alias
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode {
+		return a.ShortTag() == b.ShortTag() && a.Value == b.Value
+	}
+	if len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !nodeEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package fields
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		format             Format
+		base, ours, theirs string
+		want               string
+		conflicts          []string
+	}{
+		{
+			name:   "env different keys changed",
+			format: Env,
+			base:   "A=1\nB=2\n",
+			ours:   "A=10\nB=2\n",
+			theirs: "A=1\nB=20\n",
+			want:   "A=10\nB=20\n",
+		},
+		{
+			name:   "env add/add different keys",
+			format: Env,
+			base:   "A=1\n",
+			ours:   "A=1\nB=2\n",
+			theirs: "A=1\nC=3\n",
+			want:   "A=1\nB=2\nC=3\n",
+		},
+		{
+			name:      "env add/add same key, different values",
+			format:    Env,
+			base:      "A=1\n",
+			ours:      "A=1\nB=ours\n",
+			theirs:    "A=1\nB=theirs\n",
+			want:      "A=1\nB=ours\n",
+			conflicts: []string{"B"},
+		},
+		{
+			name:   "env add/add same key, same value",
+			format: Env,
+			base:   "A=1\n",
+			ours:   "A=1\nB=2\nC=ours\n",
+			theirs: "A=1\nB=2\n",
+			want:   "A=1\nB=2\nC=ours\n",
+		},
+		{
+			name:   "env file added on both sides",
+			format: Env,
+			base:   "",
+			ours:   "A=1\n",
+			theirs: "B=2\n",
+			want:   "A=1\nB=2\n",
+		},
+		{
+			name:      "env delete vs modify",
+			format:    Env,
+			base:      "A=1\nB=2\n",
+			ours:      "A=1\n",
+			theirs:    "A=1\nB=20\n",
+			want:      "A=1\n",
+			conflicts: []string{"B"},
+		},
+		{
+			name:      "env modify vs delete",
+			format:    Env,
+			base:      "A=1\nB=2\n",
+			ours:      "A=1\nB=20\n",
+			theirs:    "A=1\n",
+			want:      "A=1\nB=20\n",
+			conflicts: []string{"B"},
+		},
+		{
+			name:   "env delete vs unchanged",
+			format: Env,
+			base:   "A=1\nB=2\n",
+			ours:   "A=10\nB=2\n",
+			theirs: "A=1\n",
+			want:   "A=10\n",
+		},
+		{
+			name:   "env identical changes on both sides",
+			format: Env,
+			base:   "A=1\nB=2\n",
+			ours:   "A=10\nB=2\nC=3\n",
+			theirs: "A=10\nB=2\nC=3\n",
+			want:   "A=10\nB=2\nC=3\n",
+		},
+		{
+			name:   "env same change plus a different one",
+			format: Env,
+			base:   "A=1\nB=2\n",
+			ours:   "# comment\nA=10\nB=2\n",
+			theirs: "A=10\nB=20\n",
+			want:   "# comment\nA=10\nB=20\n",
+		},
+		{
+			name:   "yaml nested keys",
+			format: YAML,
+			base:   "db:\n  host: localhost\n  password: old\napi: key\n",
+			ours:   "db:\n  host: localhost\n  password: ours\napi: key\n",
+			theirs: "db:\n  host: db.internal\n  password: old\napi: key\n",
+			want:   "db:\n  host: db.internal\n  password: ours\napi: key\n",
+		},
+		{
+			name:      "yaml nested conflict",
+			format:    YAML,
+			base:      "db:\n  password: old\n  user: app\n",
+			ours:      "db:\n  password: ours\n  user: app\n",
+			theirs:    "db:\n  password: theirs\n  user: admin\n",
+			want:      "db:\n  password: ours\n  user: admin\n",
+			conflicts: []string{"db.password"},
+		},
+		{
+			name:   "yaml nested add/add and delete",
+			format: YAML,
+			base:   "db:\n  user: app\n  legacy: x\n",
+			ours:   "db:\n  user: app\n  legacy: x\n  ours: 1\n",
+			theirs: "db:\n  user: app\n  theirs: 2\n",
+			want:   "db:\n  user: app\n  ours: 1\n  theirs: 2\n",
+		},
+		{
+			name:      "yaml delete vs modify of nested object",
+			format:    YAML,
+			base:      "db:\n  user: app\ncache:\n  ttl: 1\n",
+			ours:      "db:\n  user: app\n",
+			theirs:    "db:\n  user: app\ncache:\n  ttl: 2\n",
+			want:      "db:\n  user: app\n",
+			conflicts: []string{"cache"},
+		},
+		{
+			name:   "yaml lists merge as a whole",
+			format: YAML,
+			base:   "hosts: [a]\nport: 1\n",
+			ours:   "hosts: [a, b]\nport: 1\n",
+			theirs: "hosts: [a]\nport: 2\n",
+			want:   "hosts: [a, b]\nport: 2\n",
+		},
+		{
+			name:   "json nested",
+			format: JSON,
+			base:   `{"db": {"host": "h", "password": "p"}}`,
+			ours:   `{"db": {"host": "h", "password": "ours"}}`,
+			theirs: `{"db": {"host": "h2", "password": "p"}, "new": true}`,
+			want:   "{\n  \"db\": {\n    \"host\": \"h2\",\n    \"password\": \"ours\"\n  },\n  \"new\": true\n}\n",
+		},
+		{
+			name:      "non-object root",
+			format:    JSON,
+			base:      `[1]`,
+			ours:      `[2]`,
+			theirs:    `[3]`,
+			want:      `[2]`,
+			conflicts: []string{"."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts, err := Merge(tt.format, []byte(tt.base), []byte(tt.ours), []byte(tt.theirs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(merged) != tt.want {
+				t.Errorf("merged = %q, want %q", merged, tt.want)
+			}
+			if !reflect.DeepEqual(conflicts, tt.conflicts) {
+				t.Errorf("conflicts = %q, want %q", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeUnsupported(t *testing.T) {
+	if _, _, err := Merge(TOML, []byte("a = 1"), []byte("a = 2"), []byte("a = 3")); err == nil {
+		t.Error("TOML merged without error")
+	}
+}
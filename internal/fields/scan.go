@@ -0,0 +1,508 @@
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Value — скалярное значение, найденное в файле
+type Value struct {
+	// Key — имя ключа; для элементов списка — имя ключа списка
+	Key string
+	// Start и End — положение значения в содержимом в том виде,
+	// как оно записано в файле (вместе с кавычками)
+	Start, End int
+	// Comment — комментарий в конце строки значения или строкой выше
+	Comment string
+}
+
+// Scan находит в содержимом все непустые скалярные значения в порядке
+// следования. Массивы TOML и flow-коллекции YAML считаются одним значением.
+func Scan(format Format, content []byte) ([]Value, error) {
+	switch format {
+	case Env:
+		return scanLines(content, "#"), nil
+	case INI:
+		return scanLines(content, "#;"), nil
+	case TOML:
+		return scanTOML(content), nil
+	case YAML:
+		// Построчный разбор не проверяет синтаксис, поэтому проверяем заранее
+		var doc yaml.Node
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("ошибка разбора файла: %v", err)
+		}
+		return scanYAML(content), nil
+	case JSON:
+		if !json.Valid(content) {
+			return nil, fmt.Errorf("ошибка разбора файла: неверный JSON")
+		}
+		sc := &jsonScanner{s: content}
+		if err := sc.value(""); err != nil {
+			return nil, err
+		}
+		return sc.values, nil
+	}
+	return nil, fmt.Errorf("формат файла не поддерживается")
+}
+
+// Replace заменяет найденные значения результатом replace
+func Replace(content []byte, values []Value, replace func(v Value, raw []byte) []byte) []byte {
+	var buf bytes.Buffer
+	last := 0
+	for _, v := range values {
+		buf.Write(content[last:v.Start])
+		buf.Write(replace(v, content[v.Start:v.End]))
+		last = v.End
+	}
+	buf.Write(content[last:])
+	return buf.Bytes()
+}
+
+// Unquote возвращает текст значения, найденного Scan: без кавычек и
+// escape-последовательностей, у многострочных строк TOML — без тройных
+// кавычек, у блочных скаляров YAML — без индикатора и отступа
+func Unquote(raw []byte) string {
+	s := string(raw)
+	switch {
+	case len(s) >= 6 && (strings.HasPrefix(s, `"""`) && strings.HasSuffix(s, `"""`) ||
+		strings.HasPrefix(s, "'''") && strings.HasSuffix(s, "'''")):
+		// Перевод строки сразу после открывающих кавычек не входит в значение
+		return strings.TrimPrefix(s[3:len(s)-3], "\n")
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	case strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">"):
+		lines := strings.Split(s, "\n")[1:]
+		for i := range lines {
+			lines[i] = strings.TrimSpace(lines[i])
+		}
+		if s[0] == '>' {
+			return strings.Join(lines, " ")
+		}
+		return strings.Join(lines, "\n")
+	}
+	return s
+}
+
+var envKey = regexp.MustCompile(`^[ \t]*(?:export[ \t]+)?([\w.-]+)[ \t]*=[ \t]*`)
+
+// scanLines разбирает .env и INI: ключ=значение, значения в кавычках
+// могут занимать несколько строк
+func scanLines(s []byte, commentChars string) []Value {
+	var values []Value
+	var pending string
+	for pos := 0; pos < len(s); {
+		eol := lineEnd(s, pos)
+		line := s[pos:eol]
+		trimmed := bytes.TrimSpace(line)
+
+		m := envKey.FindSubmatchIndex(line)
+		switch {
+		case len(trimmed) > 0 && strings.IndexByte(commentChars, trimmed[0]) >= 0:
+			pending = strings.TrimSpace(string(trimmed[1:]))
+		case m == nil:
+			pending = ""
+		default:
+			v := Value{Key: string(line[m[2]:m[3]]), Start: pos + m[1]}
+			v.End = scalarEnd(s, v.Start, eol, commentChars)
+			eol = lineEnd(s, v.End)
+			v.Comment = commentOr(trailingComment(s, v.End, eol, commentChars), pending)
+			if v.End > v.Start {
+				values = append(values, v)
+			}
+			pending = ""
+		}
+		pos = eol + 1
+	}
+	return values
+}
+
+var tomlKey = regexp.MustCompile(`^[ \t]*((?:[\w-]+|"[^"]*"|'[^']*')(?:[ \t]*\.[ \t]*(?:[\w-]+|"[^"]*"|'[^']*'))*)[ \t]*=[ \t]*`)
+
+// scanTOML разбирает пары ключ = значение; для составных ключей
+// (a.b.c) в Key попадает последняя часть
+func scanTOML(s []byte) []Value {
+	var values []Value
+	var pending string
+	for pos := 0; pos < len(s); {
+		eol := lineEnd(s, pos)
+		line := s[pos:eol]
+		trimmed := bytes.TrimSpace(line)
+
+		m := tomlKey.FindSubmatchIndex(line)
+		switch {
+		case len(trimmed) > 0 && trimmed[0] == '#':
+			pending = strings.TrimSpace(string(trimmed[1:]))
+		case m == nil:
+			pending = ""
+		default:
+			parts := strings.Split(string(line[m[2]:m[3]]), ".")
+			key := strings.Trim(strings.TrimSpace(parts[len(parts)-1]), `"'`)
+			v := Value{Key: key, Start: pos + m[1]}
+			switch {
+			case bytes.HasPrefix(s[v.Start:], []byte(`"""`)), bytes.HasPrefix(s[v.Start:], []byte(`'''`)):
+				delim := s[v.Start : v.Start+3]
+				v.End = len(s)
+				if i := bytes.Index(s[v.Start+3:], delim); i >= 0 {
+					v.End = v.Start + 3 + i + 3
+				}
+			case v.Start < eol && (s[v.Start] == '[' || s[v.Start] == '{'):
+				v.End = flowEnd(s, v.Start)
+			default:
+				v.End = scalarEnd(s, v.Start, eol, "#")
+			}
+			eol = lineEnd(s, v.End)
+			v.Comment = commentOr(trailingComment(s, v.End, eol, "#"), pending)
+			if v.End > v.Start {
+				values = append(values, v)
+			}
+			pending = ""
+		}
+		pos = eol + 1
+	}
+	return values
+}
+
+var yamlKey = regexp.MustCompile(`^([\w.-]+|"[^"]*"|'[^']*')[ \t]*:(?:[ \t]+|$)`)
+
+type yamlParent struct {
+	indent int
+	key    string
+}
+
+// scanYAML разбирает YAML построчно по отступам: ключи, элементы списков
+// и блочные скаляры (| и >). Якоря и теги перед значением пропускаются.
+func scanYAML(s []byte) []Value {
+	var values []Value
+	var stack []yamlParent
+	var pending string
+	for pos := 0; pos < len(s); {
+		eol := lineEnd(s, pos)
+		line := s[pos:eol]
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' || trimmed[0] == '%' ||
+			bytes.HasPrefix(trimmed, []byte("---")) || bytes.HasPrefix(trimmed, []byte("...")) {
+			pending = ""
+			if len(trimmed) > 0 && trimmed[0] == '#' {
+				pending = strings.TrimSpace(string(trimmed[1:]))
+			}
+			pos = eol + 1
+			continue
+		}
+
+		indent := countIndent(line)
+		col, item := indent, false
+		for col < len(line) && line[col] == '-' && (col+1 == len(line) || line[col+1] == ' ' || line[col+1] == '\t') {
+			item = true
+			col++
+			col += countIndent(line[col:])
+		}
+
+		var key string
+		start, parentIndent := -1, indent
+		if m := yamlKey.FindSubmatchIndex(line[col:]); m != nil {
+			key = strings.Trim(string(line[col+m[2]:col+m[3]]), `"'`)
+			stack = popYAML(stack, col)
+			stack = append(stack, yamlParent{indent: col, key: key})
+			start, parentIndent = pos+col+m[1], col
+		} else if item {
+			stack = popYAML(stack, indent+1)
+			if n := len(stack); n > 0 {
+				key = stack[n-1].key
+			}
+			start = pos + col
+		}
+		if start < 0 {
+			pending = ""
+			pos = eol + 1
+			continue
+		}
+
+		// Якоря (&name) и теги (!!str) относятся к ключу, а не к значению
+		for start < eol && (s[start] == '&' || s[start] == '!') {
+			for start < eol && s[start] != ' ' && s[start] != '\t' {
+				start++
+			}
+			for start < eol && (s[start] == ' ' || s[start] == '\t') {
+				start++
+			}
+		}
+
+		v := Value{Key: key, Start: start, End: start}
+		comment := ""
+		switch {
+		case start >= eol || s[start] == '#' || s[start] == '*':
+			// Вложенный объект, список или ссылка на якорь
+		case s[start] == '|' || s[start] == '>':
+			comment = trailingComment(s, start+1, eol, "#")
+			v.End = yamlBlockEnd(s, eol, parentIndent)
+		case s[start] == '"':
+			v.End = closingQuote(s, start+1, '"', true)
+		case s[start] == '\'':
+			// Кавычка внутри строки экранируется удвоением: 'it''s'
+			v.End = closingQuote(s, start+1, '\'', false)
+			for v.End < len(s) && s[v.End] == '\'' {
+				v.End = closingQuote(s, v.End+1, '\'', false)
+			}
+		case s[start] == '[' || s[start] == '{':
+			v.End = flowEnd(s, start)
+		default:
+			v.End = plainEnd(s, start, eol, "#")
+		}
+
+		eol = lineEnd(s, v.End)
+		if comment == "" {
+			comment = trailingComment(s, v.End, eol, "#")
+		}
+		v.Comment = commentOr(comment, pending)
+		if v.End > v.Start {
+			values = append(values, v)
+		}
+		pending = ""
+		pos = eol + 1
+	}
+	return values
+}
+
+// popYAML убирает из стека ключи с отступом не меньше indent
+func popYAML(stack []yamlParent, indent int) []yamlParent {
+	for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+		stack = stack[:len(stack)-1]
+	}
+	return stack
+}
+
+// yamlBlockEnd возвращает конец блочного скаляра, заголовок которого
+// заканчивается в eol: строки блока имеют отступ больше parentIndent
+func yamlBlockEnd(s []byte, eol, parentIndent int) int {
+	end := eol
+	for pos := eol + 1; pos < len(s); {
+		next := lineEnd(s, pos)
+		line := s[pos:next]
+		if len(bytes.TrimSpace(line)) > 0 {
+			if countIndent(line) <= parentIndent {
+				break
+			}
+			end = next
+		}
+		pos = next + 1
+	}
+	return end
+}
+
+// jsonScanner обходит JSON, запоминая положение скалярных значений
+type jsonScanner struct {
+	s      []byte
+	pos    int
+	values []Value
+}
+
+func (sc *jsonScanner) value(key string) error {
+	sc.space()
+	if sc.pos >= len(sc.s) {
+		return sc.errorf()
+	}
+	start := sc.pos
+	switch sc.s[sc.pos] {
+	case '{':
+		return sc.object()
+	case '[':
+		return sc.array(key)
+	case '"':
+		sc.pos = closingQuote(sc.s, sc.pos+1, '"', true)
+	default:
+		for sc.pos < len(sc.s) && !strings.ContainsRune(",]} \t\r\n", rune(sc.s[sc.pos])) {
+			sc.pos++
+		}
+		if sc.pos == start {
+			return sc.errorf()
+		}
+	}
+	sc.values = append(sc.values, Value{Key: key, Start: start, End: sc.pos})
+	return nil
+}
+
+func (sc *jsonScanner) object() error {
+	sc.pos++
+	for {
+		sc.space()
+		if sc.next('}') {
+			return nil
+		}
+		if sc.pos >= len(sc.s) || sc.s[sc.pos] != '"' {
+			return sc.errorf()
+		}
+		start := sc.pos
+		sc.pos = closingQuote(sc.s, sc.pos+1, '"', true)
+		var key string
+		if err := json.Unmarshal(sc.s[start:sc.pos], &key); err != nil {
+			return sc.errorf()
+		}
+		sc.space()
+		if !sc.next(':') {
+			return sc.errorf()
+		}
+		if err := sc.value(key); err != nil {
+			return err
+		}
+		sc.space()
+		if sc.next(',') {
+			continue
+		}
+		if sc.next('}') {
+			return nil
+		}
+		return sc.errorf()
+	}
+}
+
+func (sc *jsonScanner) array(key string) error {
+	sc.pos++
+	for {
+		sc.space()
+		if sc.next(']') {
+			return nil
+		}
+		if err := sc.value(key); err != nil {
+			return err
+		}
+		sc.space()
+		if sc.next(',') {
+			continue
+		}
+		if sc.next(']') {
+			return nil
+		}
+		return sc.errorf()
+	}
+}
+
+func (sc *jsonScanner) next(c byte) bool {
+	if sc.pos < len(sc.s) && sc.s[sc.pos] == c {
+		sc.pos++
+		return true
+	}
+	return false
+}
+
+func (sc *jsonScanner) space() {
+	for sc.pos < len(sc.s) && strings.ContainsRune(" \t\r\n", rune(sc.s[sc.pos])) {
+		sc.pos++
+	}
+}
+
+func (sc *jsonScanner) errorf() error {
+	return fmt.Errorf("ошибка разбора JSON в позиции %d", sc.pos)
+}
+
+// lineEnd возвращает позицию перевода строки (или конца содержимого)
+func lineEnd(s []byte, pos int) int {
+	if i := bytes.IndexByte(s[pos:], '\n'); i >= 0 {
+		return pos + i
+	}
+	return len(s)
+}
+
+func countIndent(line []byte) int {
+	n := 0
+	for n < len(line) && (line[n] == ' ' || line[n] == '\t') {
+		n++
+	}
+	return n
+}
+
+// scalarEnd возвращает конец значения в кавычках или без них
+func scalarEnd(s []byte, pos, eol int, commentChars string) int {
+	if pos >= eol {
+		return pos
+	}
+	switch s[pos] {
+	case '"':
+		return closingQuote(s, pos+1, '"', true)
+	case '\'':
+		return closingQuote(s, pos+1, '\'', false)
+	}
+	return plainEnd(s, pos, eol, commentChars)
+}
+
+// closingQuote возвращает позицию после закрывающей кавычки q.
+// Незакрытая строка продолжается до конца содержимого.
+func closingQuote(s []byte, pos int, q byte, escapes bool) int {
+	for i := pos; i < len(s); i++ {
+		if escapes && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == q {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// plainEnd возвращает конец значения без кавычек: до комментария,
+// отделенного пробелом, без пробелов в конце
+func plainEnd(s []byte, pos, eol int, commentChars string) int {
+	end := eol
+	for i := pos; i < eol; i++ {
+		if strings.IndexByte(commentChars, s[i]) >= 0 && (i == pos || s[i-1] == ' ' || s[i-1] == '\t') {
+			end = i
+			break
+		}
+	}
+	for end > pos && (s[end-1] == ' ' || s[end-1] == '\t' || s[end-1] == '\r') {
+		end--
+	}
+	return end
+}
+
+// flowEnd возвращает конец массива или объекта в скобках с учетом строк
+func flowEnd(s []byte, pos int) int {
+	depth := 0
+	for i := pos; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			i = closingQuote(s, i+1, '"', true) - 1
+		case '\'':
+			i = closingQuote(s, i+1, '\'', false) - 1
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// trailingComment возвращает текст комментария после значения на той же строке
+func trailingComment(s []byte, pos, eol int, commentChars string) string {
+	if pos >= eol {
+		return ""
+	}
+	rest := s[pos:eol]
+	if i := bytes.IndexAny(rest, commentChars); i >= 0 {
+		return strings.TrimSpace(string(rest[i+1:]))
+	}
+	return ""
+}
+
+func commentOr(comment, fallback string) string {
+	if comment != "" {
+		return comment
+	}
+	return fallback
+}
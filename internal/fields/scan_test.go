@@ -0,0 +1,167 @@
+package fields
+
+import (
+	"reflect"
+	"testing"
+)
+
+// scanned — значение из Scan в удобном для сравнения виде
+type scanned struct {
+	Key, Raw, Text, Comment string
+}
+
+func scanAll(t *testing.T, format Format, content string) []scanned {
+	t.Helper()
+	values, err := Scan(format, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]scanned, len(values))
+	for i, v := range values {
+		raw := []byte(content[v.Start:v.End])
+		got[i] = scanned{Key: v.Key, Raw: string(raw), Text: Unquote(raw), Comment: v.Comment}
+	}
+	return got
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		want    []scanned
+	}{
+		{
+			name:    "env comments and export",
+			format:  Env,
+			content: "# db password\nexport DB_PASSWORD=hunter22\nHOST=localhost # local\nEMPTY=\n",
+			want: []scanned{
+				{"DB_PASSWORD", "hunter22", "hunter22", "db password"},
+				{"HOST", "localhost", "localhost", "local"},
+			},
+		},
+		{
+			name:    "env multiline double quotes",
+			format:  Env,
+			content: "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT=1\n",
+			want: []scanned{
+				{"KEY", "\"-----BEGIN-----\nabc\n-----END-----\"", "-----BEGIN-----\nabc\n-----END-----", ""},
+				{"NEXT", "1", "1", ""},
+			},
+		},
+		{
+			name:    "env escaped quotes",
+			format:  Env,
+			content: "A=\"say \\\"hi\\\" # not comment\"\nB='single # quoted'\n",
+			want: []scanned{
+				{"A", `"say \"hi\" # not comment"`, `say "hi" # not comment`, ""},
+				{"B", "'single # quoted'", "single # quoted", ""},
+			},
+		},
+		{
+			name:    "ini sections and comments",
+			format:  INI,
+			content: "; top\n[db]\npassword = hunter22 ; inline\n# other\nuser=admin\n",
+			want: []scanned{
+				{"password", "hunter22", "hunter22", "inline"},
+				{"user", "admin", "admin", "other"},
+			},
+		},
+		{
+			name:    "yaml block scalars",
+			format:  YAML,
+			content: "cert: |\n  line1\n  line2\nfolded: >- # secret\n  a\n  b\nnext: v\n",
+			want: []scanned{
+				{"cert", "|\n  line1\n  line2", "line1\nline2", ""},
+				{"folded", ">- # secret\n  a\n  b", "a b", "secret"},
+				{"next", "v", "v", ""},
+			},
+		},
+		{
+			name:    "yaml quotes",
+			format:  YAML,
+			content: "a: \"x \\\"y\\\" # z\"\nb: 'it''s'\nc: plain # note\n",
+			want: []scanned{
+				{"a", `"x \"y\" # z"`, `x "y" # z`, ""},
+				{"b", "'it''s'", "it's", ""},
+				{"c", "plain", "plain", "note"},
+			},
+		},
+		{
+			name:    "yaml multiline double quotes",
+			format:  YAML,
+			content: "a: \"first\n  second\"\nb: 1\n",
+			want: []scanned{
+				{"a", "\"first\n  second\"", "first\n  second", ""},
+				{"b", "1", "1", ""},
+			},
+		},
+		{
+			name:    "yaml nested, lists, anchors",
+			format:  YAML,
+			content: "db:\n  password: &pw secret1\n  tokens:\n    - t1\n    - t2\n  flow: [a, b]\ncopy: *pw\n",
+			want: []scanned{
+				{"password", "secret1", "secret1", ""},
+				{"tokens", "t1", "t1", ""},
+				{"tokens", "t2", "t2", ""},
+				{"flow", "[a, b]", "[a, b]", ""},
+			},
+		},
+		{
+			name:    "json nested and escapes",
+			format:  JSON,
+			content: `{"a": {"b": "x\"y"}, "n": 5, "list": ["p", true], "e": null}`,
+			want: []scanned{
+				{"b", `"x\"y"`, `x"y`, ""},
+				{"n", "5", "5", ""},
+				{"list", `"p"`, "p", ""},
+				{"list", "true", "true", ""},
+				{"e", "null", "null", ""},
+			},
+		},
+		{
+			name:    "toml multiline and arrays",
+			format:  TOML,
+			content: "[server]\nkey = \"\"\"\nmulti\nline\"\"\"\nports = [\n  80,\n  443,\n]\ndb.password = 'p' # secret\n",
+			want: []scanned{
+				{"key", "\"\"\"\nmulti\nline\"\"\"", "multi\nline", ""},
+				{"ports", "[\n  80,\n  443,\n]", "[\n  80,\n  443,\n]", ""},
+				{"password", "'p'", "p", "secret"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scanAll(t, tt.format, tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanInvalid(t *testing.T) {
+	if _, err := Scan(JSON, []byte(`{"a": `)); err == nil {
+		t.Error("invalid JSON scanned without error")
+	}
+	if _, err := Scan(YAML, []byte("a: [1\n")); err == nil {
+		t.Error("invalid YAML scanned without error")
+	}
+	if _, err := Scan(Unknown, []byte("x")); err == nil {
+		t.Error("unknown format scanned without error")
+	}
+}
+
+func TestReplaceKeepsLayout(t *testing.T) {
+	content := "# c\nA=1 # x\nB=\"two\nlines\"\n"
+	values, err := Scan(Env, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Replace([]byte(content), values, func(v Value, raw []byte) []byte {
+		return []byte("<" + v.Key + ">")
+	})
+	if want := "# c\nA=<A> # x\nB=<B>\n"; string(got) != want {
+		t.Errorf("Replace = %q, want %q", got, want)
+	}
+}
@@ -1,22 +1,194 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Backend     string   `yaml:"backend"`
-	GPGKey      string   `yaml:"gpg_key,omitempty"`
-	ProjectName string   `yaml:"project_name,omitempty"`
-	SecretFiles []string `yaml:"secret_files,omitempty"`
-	SecretDir   string   `yaml:"secret_dir,omitempty"`
+	Backend     string      `yaml:"backend"`
+	GPGKey      string      `yaml:"gpg_key,omitempty"`
+	Recipients  []string    `yaml:"recipients,omitempty"`
+	ProjectName string      `yaml:"project_name,omitempty"`
+	SecretFiles SecretFiles `yaml:"secret_files,omitempty"`
+	SecretDir   string      `yaml:"secret_dir,omitempty"`
+
+	// Режим шифрования: file — файл целиком (по умолчанию),
+	// values — каждое значение отдельно, ключи остаются открытыми
+	Mode string `yaml:"mode,omitempty"`
+	// Метки секретных значений: если заданы, в mode: values шифруются
+	// только отмеченные значения, остальные остаются открытыми
+	Markers *Markers `yaml:"markers,omitempty"`
+
+	// Группы получателей для доступа к отдельным файлам (devs, ops, ...)
+	Groups map[string][]string `yaml:"groups,omitempty"`
+
+	// Параметры ключа проекта для secret rotate-key и ID прежних ключей
+	Key        *KeySpec `yaml:"key,omitempty"`
+	KeyHistory []string `yaml:"key_history,omitempty"`
+
+	// Именованные окружения (dev, staging, prod) со своими ключами и файлами
+	Environments map[string]*Environment `yaml:"environments,omitempty"`
+
+	Vault     *VaultConfig     `yaml:"vault,omitempty"`
+	Bitwarden *BitwardenConfig `yaml:"bitwarden,omitempty"`
+}
+
+// Environment — окружение проекта со своим ключом (или получателями)
+// и своим набором файлов. Пустые gpg_key и recipients наследуются из
+// основного конфига.
+type Environment struct {
+	GPGKey      string      `yaml:"gpg_key,omitempty"`
+	Recipients  []string    `yaml:"recipients,omitempty"`
+	SecretFiles SecretFiles `yaml:"secret_files,omitempty"`
+}
+
+// KeySpec — параметры генерации ключа проекта, выбранные в secret init.
+// Парольная фраза не сохраняется.
+type KeySpec struct {
+	Type   string `yaml:"type"`
+	Length int    `yaml:"length,omitempty"`
+	Expire string `yaml:"expire,omitempty"`
+}
+
+// Markers — как отмечаются секретные значения: комментарием к ключу
+// (# secret) или суффиксом имени ключа (DB_PASSWORD_secret).
+// Пустой блок markers: {} включает метки по умолчанию.
+type Markers struct {
+	Comment string `yaml:"comment,omitempty"`
+	Suffix  string `yaml:"suffix,omitempty"`
+}
+
+// VaultConfig — настройки бэкенда HashiCorp Vault (KV v2).
+// Токены и secret_id в конфиг не сохраняются, они берутся из окружения.
+type VaultConfig struct {
+	Address     string `yaml:"address,omitempty"`
+	Namespace   string `yaml:"namespace,omitempty"`
+	Mount       string `yaml:"mount,omitempty"`
+	Path        string `yaml:"path,omitempty"`
+	Auth        string `yaml:"auth,omitempty"`
+	RoleID      string `yaml:"role_id,omitempty"`
+	AppRolePath string `yaml:"approle_path,omitempty"`
+}
+
+// BitwardenConfig — куда бэкенд Bitwarden складывает заметки с секретами
+type BitwardenConfig struct {
+	OrganizationID string `yaml:"organization_id,omitempty"`
+	CollectionID   string `yaml:"collection_id,omitempty"`
+	FolderID       string `yaml:"folder_id,omitempty"`
 }
 
+// DefaultBackend используется, если в конфиге не указан бэкенд
+const DefaultBackend = "gpg"
+
+// Режимы шифрования
+const (
+	ModeFile   = "file"
+	ModeValues = "values"
+)
+
+// Метки секретных значений по умолчанию
+const (
+	DefaultMarkerComment = "secret"
+	DefaultMarkerSuffix  = "_secret"
+)
+
 var DefaultSecretFiles = []string{".env", "dev.env", "config.json", ".config.yaml"}
 
+// FileRecipients возвращает получателей конкретного файла: объединение
+// участников его групп или, если группы не заданы, всех получателей проекта
+func (c *Config) FileRecipients(file string) ([]string, error) {
+	entry := c.SecretFiles.Find(file)
+	if entry == nil || len(entry.Groups) == 0 {
+		return c.EncryptionRecipients(), nil
+	}
+
+	seen := map[string]bool{}
+	var recipients []string
+	for _, group := range entry.Groups {
+		members, ok := c.Groups[group]
+		if !ok {
+			return nil, fmt.Errorf("группа %q для %s не описана в groups", group, entry.Pattern)
+		}
+		for _, m := range members {
+			if !seen[m] {
+				seen[m] = true
+				recipients = append(recipients, m)
+			}
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("в группах %s для %s нет получателей", strings.Join(entry.Groups, ", "), entry.Pattern)
+	}
+	return recipients, nil
+}
+
+// Marked сообщает, отмечено ли значение ключа key с комментарием comment
+// как секретное. Суффикс сравнивается без учета регистра (DB_PASSWORD_SECRET),
+// комментарий — по первому слову (# secret, # Secret: ротация раз в квартал).
+// Без настроенных меток секретными считаются все значения.
+func (m *Markers) Marked(key, comment string) bool {
+	if m == nil {
+		return true
+	}
+	marker, suffix := m.Comment, m.Suffix
+	if marker == "" && suffix == "" {
+		marker, suffix = DefaultMarkerComment, DefaultMarkerSuffix
+	}
+	if suffix != "" && len(key) >= len(suffix) && strings.EqualFold(key[len(key)-len(suffix):], suffix) {
+		return true
+	}
+	if words := strings.Fields(comment); marker != "" && len(words) > 0 {
+		return strings.EqualFold(strings.TrimRight(words[0], ":,."), marker)
+	}
+	return false
+}
+
+// EncryptionRecipients возвращает получателей, для которых шифруются файлы.
+// Для конфигов без recipients используется единственный ключ проекта gpg_key.
+func (c *Config) EncryptionRecipients() []string {
+	if len(c.Recipients) > 0 {
+		return c.Recipients
+	}
+	if c.GPGKey != "" {
+		return []string{c.GPGKey}
+	}
+	return nil
+}
+
+// ForEnv возвращает копию конфига, в которой ключ, получатели и файлы
+// заменены настройками окружения name. Пустое имя возвращает сам конфиг.
+func (c *Config) ForEnv(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	env, ok := c.Environments[name]
+	if !ok {
+		names := make([]string, 0, len(c.Environments))
+		for n := range c.Environments {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("окружение %q не найдено (доступны: %s)", name, strings.Join(names, ", "))
+	}
+
+	if env == nil {
+		env = &Environment{}
+	}
+	envCfg := *c
+	if env.GPGKey != "" || len(env.Recipients) > 0 {
+		envCfg.GPGKey = env.GPGKey
+		envCfg.Recipients = env.Recipients
+	}
+	envCfg.SecretFiles = env.SecretFiles
+	return &envCfg, nil
+}
+
 func LoadConfig() (*Config, error) {
 	configPath := filepath.Join(".secret", "config.yaml")
 	data, err := os.ReadFile(configPath)
@@ -25,8 +197,19 @@ func LoadConfig() (*Config, error) {
 	}
 
 	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
-	return &cfg, err
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = DefaultBackend
+	}
+	// Пустая запись окружения (staging:) — окружение без своих настроек
+	for name, env := range cfg.Environments {
+		if env == nil {
+			cfg.Environments[name] = &Environment{}
+		}
+	}
+	return &cfg, nil
 }
 
 func SaveConfig(cfg *Config) error {
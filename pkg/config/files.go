@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretFile — шаблон секретного файла и группы, которым он доступен.
+// В YAML записывается строкой (config.json) или парой (prod.env: [ops]).
+type SecretFile struct {
+	Pattern string
+	Groups  []string
+}
+
+// SecretFiles — список секретных файлов из secret_files
+type SecretFiles []SecretFile
+
+// NewSecretFiles создает список файлов без ограничений доступа
+func NewSecretFiles(patterns []string) SecretFiles {
+	files := make(SecretFiles, len(patterns))
+	for i, p := range patterns {
+		files[i] = SecretFile{Pattern: p}
+	}
+	return files
+}
+
+// Patterns возвращает шаблоны файлов
+func (s SecretFiles) Patterns() []string {
+	patterns := make([]string, len(s))
+	for i, f := range s {
+		patterns[i] = f.Pattern
+	}
+	return patterns
+}
+
+// Find возвращает запись, шаблону которой соответствует файл, или nil.
+// Шаблон сравнивается с последними компонентами пути, поэтому
+// ./config/prod.env и /abs/path/config/prod.env подходят под config/prod.env.
+func (s SecretFiles) Find(file string) *SecretFile {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(file)), "/")
+	for i := range s {
+		pattern := filepath.ToSlash(filepath.Clean(s[i].Pattern))
+		n := strings.Count(pattern, "/") + 1
+		if n > len(parts) {
+			continue
+		}
+		tail := strings.Join(parts[len(parts)-n:], "/")
+		if ok, _ := filepath.Match(pattern, tail); ok {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+func (f SecretFile) MarshalYAML() (any, error) {
+	if len(f.Groups) == 0 {
+		return f.Pattern, nil
+	}
+	return map[string][]string{f.Pattern: f.Groups}, nil
+}
+
+func (f *SecretFile) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		f.Groups = nil
+		return node.Decode(&f.Pattern)
+	case yaml.MappingNode:
+		if len(node.Content) != 2 {
+			return fmt.Errorf("строка %d: запись secret_files должна содержать один файл", node.Line)
+		}
+		if err := node.Content[0].Decode(&f.Pattern); err != nil {
+			return err
+		}
+		// Допускаем как список групп, так и одну группу строкой
+		value := node.Content[1]
+		if value.Kind == yaml.ScalarNode {
+			var group string
+			if err := value.Decode(&group); err != nil {
+				return err
+			}
+			f.Groups = []string{group}
+			return nil
+		}
+		return value.Decode(&f.Groups)
+	default:
+		return fmt.Errorf("строка %d: неверная запись secret_files", node.Line)
+	}
+}